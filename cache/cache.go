@@ -0,0 +1,556 @@
+// Package cache decorates a model with a read-through cache of query results.
+//
+// First, All and Count results are stored under a hash of the collection, its
+// version and the query params. Every write through the model moves the
+// collection to a new version, so entries cached before it are never read
+// again and age out of the store. Results holding preloaded relations are not
+// cached, as the related collections have versions of their own.
+//
+//	users := cache.New(model, "users", cache.NewLRU(10_000), cache.WithTTL(time.Minute))
+//
+// Writes made around the decorator, by another service or directly on the
+// inner model, are only seen once the TTL expires, unless the other writers
+// share the store and also go through a decorator.
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neghi-go/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const defaultTTL = time.Minute
+
+var errPreloaded = errors.New("cache: results holding preloaded relations are not cached")
+
+type Option func(*config)
+
+type config struct {
+	ttl time.Duration
+}
+
+// WithTTL sets how long results are cached, one minute by default. Zero
+// keeps them until evicted by the store.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.ttl = ttl
+	}
+}
+
+// Model is a caching database.Model.
+type Model[T any] struct {
+	inner      database.Model[T]
+	collection string
+	store      Store
+	ttl        time.Duration
+	ctx        context.Context
+}
+
+var _ database.Model[struct{}] = (*Model[struct{}])(nil)
+
+// New wraps inner, the model of collection, caching its results in store.
+func New[T any](inner database.Model[T], collection string, store Store, opts ...Option) *Model[T] {
+	cfg := config{ttl: defaultTTL}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return &Model[T]{
+		inner:      inner,
+		collection: collection,
+		store:      store,
+		ttl:        cfg.ttl,
+		ctx:        context.Background(),
+	}
+}
+
+// WithContext implements database.Model.
+func (m *Model[T]) WithContext(ctx context.Context) database.Model[T] {
+	m.ctx = ctx
+	m.inner = m.inner.WithContext(ctx)
+	return m
+}
+
+// Bind implements database.Binder, binding a handle of the inner model when
+// it is a Binder too.
+func (m *Model[T]) Bind(ctx context.Context) database.Model[T] {
+	handle := *m
+	handle.ctx = ctx
+	if b, ok := m.inner.(database.Binder[T]); ok {
+		handle.inner = b.Bind(ctx)
+	} else {
+		handle.inner = m.inner.WithContext(ctx)
+	}
+	return &handle
+}
+
+// Identity implements database.Identifier with the identity of the inner
+// model, or the cached collection when it has none.
+func (m *Model[T]) Identity(ctx context.Context) (string, error) {
+	if i, ok := m.inner.(database.Identifier); ok {
+		return i.Identity(ctx)
+	}
+	return m.collection, nil
+}
+
+// Query implements database.Model.
+func (m *Model[T]) Query(query_params ...database.Params) database.Query[T] {
+	return &query[T]{model: m, ctx: m.ctx, params: query_params}
+}
+
+// Save implements database.Model.
+func (m *Model[T]) Save(doc ...T) error {
+	return m.invalidate(m.ctx, m.inner.Save(doc...))
+}
+
+// ExecRaw implements database.Model.
+func (m *Model[T]) ExecRaw() error {
+	return m.invalidate(m.ctx, m.inner.ExecRaw())
+}
+
+// Scope implements database.Model.
+func (m *Model[T]) Scope(name string, params ...database.Params) database.Model[T] {
+	m.inner = m.inner.Scope(name, params...)
+	return m
+}
+
+// DefaultScope implements database.Model.
+func (m *Model[T]) DefaultScope(params ...database.Params) database.Model[T] {
+	m.inner = m.inner.DefaultScope(params...)
+	return m
+}
+
+// Watch implements database.Model.
+func (m *Model[T]) Watch(ctx context.Context, params ...database.Params) (<-chan database.ChangeEvent[T], error) {
+	return m.inner.Watch(ctx, params...)
+}
+
+// Invalidate drops every cached result of the collection.
+func (m *Model[T]) Invalidate(ctx context.Context) error {
+	return m.invalidate(ctx, nil)
+}
+
+// invalidate moves the collection to a new version after a write. It runs
+// even when the write failed, as part of it may have been applied.
+func (m *Model[T]) invalidate(ctx context.Context, err error) error {
+	if verr := m.store.Set(ctx, m.versionKey(), newVersion(), 0); verr != nil && err == nil {
+		return fmt.Errorf("cache: invalidating %s: %w", m.collection, verr)
+	}
+	return err
+}
+
+func (m *Model[T]) versionKey() string {
+	return "version:" + m.collection
+}
+
+// version returns the current version of the collection, starting a new one
+// when the store has none, for instance after evicting it.
+func (m *Model[T]) version(ctx context.Context) (string, error) {
+	v, ok, err := m.store.Get(ctx, m.versionKey())
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return string(v), nil
+	}
+	version := newVersion()
+	if err := m.store.Set(ctx, m.versionKey(), version, 0); err != nil {
+		return "", err
+	}
+	return string(version), nil
+}
+
+func newVersion() []byte {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return []byte(hex.EncodeToString(b))
+}
+
+type query[T any] struct {
+	model  *Model[T]
+	ctx    context.Context
+	params []database.Params
+	// update holds the operators added by the update builder, replayed on
+	// the inner query by Apply and ApplyMany
+	update []func(database.Query[T]) database.Query[T]
+}
+
+func (q *query[T]) inner() database.Query[T] {
+	return q.model.inner.Query(q.params...)
+}
+
+// cached returns the result of op from the store, or runs fetch and stores
+// its result. Store failures fall back to fetch.
+func cached[T any, R any](q *query[T], op string, fetch func() (R, error)) (R, error) {
+	version, err := q.model.version(q.ctx)
+	if err != nil {
+		return fetch()
+	}
+	tenant, _ := database.TenantFrom(q.ctx)
+	key := cacheKey(q.model.collection, version, tenant, op, q.params)
+
+	if b, ok, err := q.model.store.Get(q.ctx, key); err == nil && ok {
+		var res R
+		if err := decodeResult[T](b, &res); err == nil {
+			return res, nil
+		}
+	}
+
+	res, err := fetch()
+	if err != nil {
+		return res, err
+	}
+	if b, err := encodeResult[T](res); err == nil {
+		q.model.store.Set(q.ctx, key, b, q.model.ttl)
+	}
+	return res, nil
+}
+
+// cacheKey hashes a query into a key independent of the order of its
+// filters. Sort keys keep their order as it changes the result, and the
+// tenant is part of the key as it scopes the query on tenant aware models.
+func cacheKey(collection, version, tenant, op string, params []database.Params) string {
+	var filters, others []string
+	for _, param := range params {
+		qs := param()
+		if qs.Key() == database.QueryFilter {
+			filters = append(filters, filterKey(qs))
+		} else {
+			others = append(others, fmt.Sprintf("%s|%T|%+v", qs.Key(), qs.Value(), qs.Value()))
+		}
+	}
+	slices.Sort(filters)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n", collection, version, tenant, op)
+	h.Write([]byte(strings.Join(filters, "\n")))
+	h.Write([]byte("\n--\n"))
+	h.Write([]byte(strings.Join(others, "\n")))
+	return "query:" + collection + ":" + hex.EncodeToString(h.Sum(nil))
+}
+
+// filterKey renders a filter for the cache key as BSON, which keeps the type
+// of its value, so 123, int64(123) and "123" never share a key. Maps are
+// encoded with sorted keys to render equal filters alike.
+func filterKey(qs database.QueryStruct) string {
+	if f, ok := qs.Value().(database.FilterStruct); ok {
+		if b, err := bson.Marshal(bson.D{{Key: f.Key(), Value: canonical(f.Value())}}); err == nil {
+			return fmt.Sprintf("%s|%x", qs.Key(), b)
+		}
+	}
+	return fmt.Sprintf("%s|%T|%#v", qs.Key(), qs.Value(), qs.Value())
+}
+
+// canonical replaces the maps held by value with documents sorted by key.
+func canonical(value any) any {
+	if value == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(value)
+	switch {
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+		res := make(bson.D, 0, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			res = append(res, bson.E{Key: iter.Key().String(), Value: canonical(iter.Value().Interface())})
+		}
+		slices.SortFunc(res, func(a, b bson.E) int { return strings.Compare(a.Key, b.Key) })
+		return res
+	case rv.Type() == reflect.TypeOf(bson.D{}):
+		res := make(bson.D, 0, rv.Len())
+		for _, e := range value.(bson.D) {
+			res = append(res, bson.E{Key: e.Key, Value: canonical(e.Value)})
+		}
+		return res
+	case (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() == reflect.Interface:
+		res := make(bson.A, rv.Len())
+		for i := range res {
+			res[i] = canonical(rv.Index(i).Interface())
+		}
+		return res
+	}
+	return value
+}
+
+// encodeResult encodes the result of a query for the store. Documents go
+// through the codec of the model and BSON, so they read back with the types
+// and fields a fetch returns. Documents holding preloaded relations are not
+// cached, as the related collections change without moving the version of
+// this one.
+func encodeResult[T any](res any) ([]byte, error) {
+	relations, err := database.RelationsOf(*new(T))
+	if err != nil {
+		return nil, err
+	}
+	switch v := res.(type) {
+	case int64:
+		return bson.Marshal(bson.D{{Key: "count", Value: v}})
+	case *T:
+		doc, err := encodeDoc(v, relations)
+		if err != nil {
+			return nil, err
+		}
+		return bson.Marshal(bson.D{{Key: "doc", Value: doc}})
+	case []*T:
+		var docs bson.A
+		if v != nil {
+			docs = bson.A{}
+		}
+		for _, d := range v {
+			doc, err := encodeDoc(d, relations)
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, doc)
+		}
+		return bson.Marshal(bson.D{{Key: "docs", Value: docs}})
+	}
+	return nil, fmt.Errorf("cache: unsupported result %T", res)
+}
+
+// decodeResult decodes into res a result stored by encodeResult.
+func decodeResult[T any](b []byte, res any) error {
+	var stored struct {
+		Count int64  `bson:"count"`
+		Doc   bson.D `bson:"doc"`
+		Docs  bson.A `bson:"docs"`
+	}
+	if err := bson.Unmarshal(b, &stored); err != nil {
+		return err
+	}
+	switch v := res.(type) {
+	case *int64:
+		*v = stored.Count
+	case **T:
+		doc, err := decodeDoc[T](stored.Doc)
+		if err != nil {
+			return err
+		}
+		*v = doc
+	case *[]*T:
+		if stored.Docs == nil {
+			*v = nil
+			return nil
+		}
+		*v = make([]*T, 0, len(stored.Docs))
+		for _, d := range stored.Docs {
+			if d == nil {
+				*v = append(*v, nil)
+				continue
+			}
+			raw, ok := d.(bson.D)
+			if !ok {
+				return fmt.Errorf("cache: invalid document %T", d)
+			}
+			doc, err := decodeDoc[T](raw)
+			if err != nil {
+				return err
+			}
+			*v = append(*v, doc)
+		}
+	default:
+		return fmt.Errorf("cache: unsupported result %T", res)
+	}
+	return nil
+}
+
+func encodeDoc[T any](doc *T, relations []database.Relation) (bson.D, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	for _, r := range relations {
+		if !reflect.ValueOf(doc).Elem().FieldByName(r.Name).IsZero() {
+			return nil, errPreloaded
+		}
+	}
+	m, err := database.EncodeModel(*doc)
+	if err != nil {
+		return nil, err
+	}
+	res := make(bson.D, 0, len(m))
+	for _, p := range m {
+		val := p.Value
+		if id, ok := val.(uuid.UUID); ok {
+			val = bson.Binary{Subtype: bson.TypeBinaryUUID, Data: id[:]}
+		}
+		res = append(res, bson.E{Key: p.Key, Value: val})
+	}
+	return res, nil
+}
+
+func decodeDoc[T any](doc bson.D) (*T, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	m := make(database.M, 0, len(doc))
+	for _, e := range doc {
+		val := e.Value
+		switch v := val.(type) {
+		case bson.DateTime:
+			val = v.Time()
+		case bson.Binary:
+			if v.Subtype == bson.TypeBinaryUUID {
+				id, err := uuid.FromBytes(v.Data)
+				if err != nil {
+					return nil, err
+				}
+				val = id
+			}
+		}
+		m = append(m, database.P{Key: e.Key, Value: val})
+	}
+	var res T
+	if err := database.DecodeModel(&res, m); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Count implements database.Query.
+func (q *query[T]) Count() (int64, error) {
+	return cached(q, "count", q.inner().Count)
+}
+
+// First implements database.Query.
+func (q *query[T]) First() (*T, error) {
+	return cached(q, "first", q.inner().First)
+}
+
+// All implements database.Query.
+func (q *query[T]) All() ([]*T, error) {
+	return cached(q, "all", q.inner().All)
+}
+
+// Iter implements database.Query. Streams are not cached.
+func (q *query[T]) Iter() iter.Seq2[*T, error] {
+	return q.inner().Iter()
+}
+
+// Each implements database.Query. Streams are not cached.
+func (q *query[T]) Each(fn func(*T) error) error {
+	return q.inner().Each(fn)
+}
+
+// Page implements database.Query.
+func (q *query[T]) Page(size int64, token string) (database.Page[T], error) {
+	return q.inner().Page(size, token)
+}
+
+// Paginate implements database.Query.
+func (q *query[T]) Paginate(page, perPage int64) (database.Paginated[T], error) {
+	return q.inner().Paginate(page, perPage)
+}
+
+// Distinct implements database.Query.
+func (q *query[T]) Distinct(field string) ([]any, error) {
+	return q.inner().Distinct(field)
+}
+
+// Explain implements database.Query.
+func (q *query[T]) Explain(verbosity database.ExplainVerbosity) (database.Plan, error) {
+	return q.inner().Explain(verbosity)
+}
+
+// Update implements database.Query.
+func (q *query[T]) Update(doc T) error {
+	return q.model.invalidate(q.ctx, q.inner().Update(doc))
+}
+
+// UpdateMany implements database.Query.
+func (q *query[T]) UpdateMany(doc T) error {
+	return q.model.invalidate(q.ctx, q.inner().UpdateMany(doc))
+}
+
+// UpdateFields implements database.Query.
+func (q *query[T]) UpdateFields(doc T, fields ...string) error {
+	return q.model.invalidate(q.ctx, q.inner().UpdateFields(doc, fields...))
+}
+
+func (q *query[T]) addUpdate(fn func(database.Query[T]) database.Query[T]) database.Query[T] {
+	q.update = append(q.update, fn)
+	return q
+}
+
+// Set implements database.Query.
+func (q *query[T]) Set(field string, value any) database.Query[T] {
+	return q.addUpdate(func(in database.Query[T]) database.Query[T] { return in.Set(field, value) })
+}
+
+// Unset implements database.Query.
+func (q *query[T]) Unset(field string) database.Query[T] {
+	return q.addUpdate(func(in database.Query[T]) database.Query[T] { return in.Unset(field) })
+}
+
+// Inc implements database.Query.
+func (q *query[T]) Inc(field string, by any) database.Query[T] {
+	return q.addUpdate(func(in database.Query[T]) database.Query[T] { return in.Inc(field, by) })
+}
+
+// Push implements database.Query.
+func (q *query[T]) Push(field string, values ...any) database.Query[T] {
+	return q.addUpdate(func(in database.Query[T]) database.Query[T] { return in.Push(field, values...) })
+}
+
+// Pull implements database.Query.
+func (q *query[T]) Pull(field string, value any) database.Query[T] {
+	return q.addUpdate(func(in database.Query[T]) database.Query[T] { return in.Pull(field, value) })
+}
+
+// AddToSet implements database.Query.
+func (q *query[T]) AddToSet(field string, values ...any) database.Query[T] {
+	return q.addUpdate(func(in database.Query[T]) database.Query[T] { return in.AddToSet(field, values...) })
+}
+
+// Min implements database.Query.
+func (q *query[T]) Min(field string, value any) database.Query[T] {
+	return q.addUpdate(func(in database.Query[T]) database.Query[T] { return in.Min(field, value) })
+}
+
+// Max implements database.Query.
+func (q *query[T]) Max(field string, value any) database.Query[T] {
+	return q.addUpdate(func(in database.Query[T]) database.Query[T] { return in.Max(field, value) })
+}
+
+// CurrentDate implements database.Query.
+func (q *query[T]) CurrentDate(field string) database.Query[T] {
+	return q.addUpdate(func(in database.Query[T]) database.Query[T] { return in.CurrentDate(field) })
+}
+
+func (q *query[T]) built() database.Query[T] {
+	in := q.inner()
+	for _, fn := range q.update {
+		in = fn(in)
+	}
+	return in
+}
+
+// Apply implements database.Query.
+func (q *query[T]) Apply() error {
+	return q.model.invalidate(q.ctx, q.built().Apply())
+}
+
+// ApplyMany implements database.Query.
+func (q *query[T]) ApplyMany() error {
+	return q.model.invalidate(q.ctx, q.built().ApplyMany())
+}
+
+// Delete implements database.Query.
+func (q *query[T]) Delete() error {
+	return q.model.invalidate(q.ctx, q.inner().Delete())
+}
+
+// DeleteMany implements database.Query.
+func (q *query[T]) DeleteMany() error {
+	return q.model.invalidate(q.ctx, q.inner().DeleteMany())
+}
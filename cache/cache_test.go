@@ -0,0 +1,272 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/neghi-go/database"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	Name string `db:"name"`
+}
+
+// fakeModel keeps its documents in memory, ignoring params, and counts the
+// reads reaching it.
+type fakeModel struct {
+	database.Model[item]
+	docs  []item
+	sets  []string
+	reads int
+	err   error
+}
+
+func (f *fakeModel) WithContext(ctx context.Context) database.Model[item] { return f }
+func (f *fakeModel) Query(...database.Params) database.Query[item]        { return &fakeQuery{m: f} }
+func (f *fakeModel) ExecRaw() error                                       { return nil }
+func (f *fakeModel) Save(doc ...item) error {
+	f.docs = append(f.docs, doc...)
+	return f.err
+}
+
+// fakeQuery implements the methods the cache calls, the embedded nil
+// interface stands in for the others.
+type fakeQuery struct {
+	database.Query[item]
+	m *fakeModel
+}
+
+func (q *fakeQuery) Count() (int64, error) {
+	q.m.reads++
+	return int64(len(q.m.docs)), nil
+}
+func (q *fakeQuery) First() (*item, error) {
+	q.m.reads++
+	if len(q.m.docs) == 0 {
+		return nil, errors.New("not found")
+	}
+	return &q.m.docs[0], nil
+}
+func (q *fakeQuery) All() ([]*item, error) {
+	q.m.reads++
+	var res []*item
+	for i := range q.m.docs {
+		res = append(res, &q.m.docs[i])
+	}
+	return res, nil
+}
+func (q *fakeQuery) Set(field string, value any) database.Query[item] {
+	q.m.sets = append(q.m.sets, value.(string))
+	return q
+}
+func (q *fakeQuery) Apply() error {
+	for i := range q.m.docs {
+		q.m.docs[i].Name = q.m.sets[len(q.m.sets)-1]
+	}
+	return nil
+}
+func (q *fakeQuery) Delete() error {
+	q.m.docs = q.m.docs[:0]
+	return nil
+}
+func (q *fakeQuery) DeleteMany() error { return q.Delete() }
+
+func TestModel(t *testing.T) {
+	inner := &fakeModel{docs: []item{{Name: "a"}}}
+	model := New[item](inner, "items", NewLRU(100))
+
+	byName := database.WithFilter("name", "a")
+	all, err := model.Query(byName).All()
+	require.NoError(t, err)
+	require.Equal(t, []*item{{Name: "a"}}, all)
+	_, err = model.Query(byName).All()
+	require.NoError(t, err)
+	require.Equal(t, 1, inner.reads, "second read is served from the cache")
+
+	count, err := model.Query(byName).Count()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+	require.Equal(t, 2, inner.reads, "operations are cached separately")
+
+	_, err = model.Query(database.WithFilter("name", "b")).All()
+	require.NoError(t, err)
+	require.Equal(t, 3, inner.reads, "params are part of the key")
+
+	require.NoError(t, model.Save(item{Name: "b"}))
+	all, err = model.Query(byName).All()
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	require.Equal(t, 4, inner.reads, "writes invalidate")
+
+	require.NoError(t, model.Query(byName).DeleteMany())
+	_, err = model.Query(byName).First()
+	require.Error(t, err)
+	_, err = model.Query(byName).First()
+	require.Error(t, err)
+	require.Equal(t, 6, inner.reads, "errors are not cached")
+}
+
+func TestModelApply(t *testing.T) {
+	inner := &fakeModel{docs: []item{{Name: "a"}}}
+	model := New[item](inner, "items", NewLRU(100))
+
+	_, err := model.Query().First()
+	require.NoError(t, err)
+
+	q := model.Query().Set("name", "b")
+	require.Empty(t, inner.sets, "operators are replayed on Apply")
+	require.NoError(t, q.Apply())
+	require.Equal(t, []string{"b"}, inner.sets)
+
+	got, err := model.Query().First()
+	require.NoError(t, err)
+	require.Equal(t, "b", got.Name)
+}
+
+func TestModelFailedWriteInvalidates(t *testing.T) {
+	errFailed := errors.New("failed")
+	inner := &fakeModel{docs: []item{{Name: "a"}}}
+	model := New[item](inner, "items", NewLRU(100))
+
+	_, err := model.Query().Count()
+	require.NoError(t, err)
+
+	inner.err = errFailed
+	require.ErrorIs(t, model.Save(item{Name: "b"}), errFailed)
+
+	count, err := model.Query().Count()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+}
+
+func TestModelSharedStore(t *testing.T) {
+	store := NewLRU(100)
+	inner := &fakeModel{docs: []item{{Name: "a"}}}
+	reader := New[item](inner, "items", store)
+	writer := New[item](inner, "items", store)
+
+	_, err := reader.Query().All()
+	require.NoError(t, err)
+	require.NoError(t, writer.Save(item{Name: "b"}))
+
+	all, err := reader.Query().All()
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+}
+
+func Test_cacheKey(t *testing.T) {
+	a := database.WithFilter("a", 1)
+	b := database.WithFilter("b", "x")
+	asc := database.WithOrder("a", database.ASC)
+	desc := database.WithOrder("b", database.DESC)
+
+	key := cacheKey("items", "v1", "", "all", []database.Params{a, b, asc, desc})
+	require.Equal(t, key, cacheKey("items", "v1", "", "all", []database.Params{b, a, asc, desc}))
+	require.NotEqual(t, key, cacheKey("items", "v1", "", "all", []database.Params{a, b, desc, asc}))
+	require.NotEqual(t, key, cacheKey("items", "v2", "", "all", []database.Params{a, b, asc, desc}))
+	require.NotEqual(t, key, cacheKey("items", "v1", "", "count", []database.Params{a, b, asc, desc}))
+	require.NotEqual(t, key, cacheKey("items", "v1", "", "all", []database.Params{a, database.WithFilter("b", "y"), asc, desc}))
+	require.NotEqual(t, key, cacheKey("items", "v1", "", "all", []database.Params{a, b, asc, desc, database.WithLimit(5)}))
+	require.NotEqual(t, key, cacheKey("items", "v1", "acme", "all", []database.Params{a, b, asc, desc}))
+
+	// filter values keep their type
+	keys := map[string]bool{}
+	for _, v := range []any{123, "123", int64(123), 123.0} {
+		keys[cacheKey("items", "v1", "", "all", []database.Params{database.WithFilter("a", v)})] = true
+	}
+	require.Len(t, keys, 4)
+	require.Equal(t,
+		cacheKey("items", "v1", "", "all", []database.Params{database.WithFilter("a", map[string]any{"$gt": 1, "$lt": 5})}),
+		cacheKey("items", "v1", "", "all", []database.Params{database.WithFilter("a", map[string]any{"$lt": 5, "$gt": 1})}))
+}
+
+// record has fields a JSON round trip would lose or change.
+type record struct {
+	ID       string      `db:"mongoid"`
+	Secret   string      `db:"secret" json:"-"`
+	Extra    interface{} `db:"extra"`
+	Created  time.Time   `db:"created"`
+	AuthorID string      `db:"author_id"`
+	Author   *item       `db:"-" rel:"belongs_to,ref=items,local=author_id"`
+}
+
+// recordModel serves docs, counting the reads reaching it.
+type recordModel struct {
+	database.Model[record]
+	docs  []*record
+	reads int
+}
+
+func (f *recordModel) Query(...database.Params) database.Query[record] { return &recordQuery{m: f} }
+
+type recordQuery struct {
+	database.Query[record]
+	m *recordModel
+}
+
+func (q *recordQuery) All() ([]*record, error) {
+	q.m.reads++
+	return q.m.docs, nil
+}
+
+func TestModelCodec(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC).Local()
+	inner := &recordModel{docs: []*record{{
+		ID:      "1",
+		Secret:  "s3cret",
+		Extra:   int32(7),
+		Created: created,
+	}}}
+	model := New[record](inner, "records", NewLRU(100))
+
+	miss, err := model.Query().All()
+	require.NoError(t, err)
+	hit, err := model.Query().All()
+	require.NoError(t, err)
+	require.Equal(t, 1, inner.reads)
+	require.Equal(t, miss[0].Secret, hit[0].Secret)
+	require.Equal(t, miss[0].Extra, hit[0].Extra)
+	require.True(t, miss[0].Created.Equal(hit[0].Created))
+
+	// preloaded relations live in other collections and are not cached
+	inner.docs[0].Author = &item{Name: "a"}
+	require.NoError(t, model.Invalidate(context.Background()))
+	for range 2 {
+		_, err = model.Query().All()
+		require.NoError(t, err)
+	}
+	require.Equal(t, 3, inner.reads)
+}
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	lru := NewLRU(2)
+	lru.now = func() time.Time { return now }
+
+	require.NoError(t, lru.Set(ctx, "a", []byte("1"), 0))
+	require.NoError(t, lru.Set(ctx, "b", []byte("2"), time.Second))
+	_, ok, _ := lru.Get(ctx, "a")
+	require.True(t, ok)
+
+	// b is now the least recently used
+	require.NoError(t, lru.Set(ctx, "c", []byte("3"), 0))
+	_, ok, _ = lru.Get(ctx, "b")
+	require.False(t, ok)
+	require.Equal(t, 2, lru.Len())
+
+	require.NoError(t, lru.Set(ctx, "c", []byte("4"), time.Second))
+	v, ok, _ := lru.Get(ctx, "c")
+	require.True(t, ok)
+	require.Equal(t, []byte("4"), v)
+
+	now = now.Add(time.Second)
+	_, ok, _ = lru.Get(ctx, "c")
+	require.False(t, ok, "expired")
+	_, ok, _ = lru.Get(ctx, "a")
+	require.True(t, ok, "no ttl")
+	require.Equal(t, 1, lru.Len())
+}
@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Store holds encoded query results. Implementations backed by a shared
+// service such as Redis let several processes see each other's invalidations.
+type Store interface {
+	// Get returns the value stored under key, and false when there is none
+	// or it expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key. A zero ttl keeps it until evicted.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// LRU is an in-process Store keeping at most capacity entries, evicting the
+// least recently used one first.
+type LRU struct {
+	capacity int
+	now      func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+var _ Store = (*LRU)(nil)
+
+func NewLRU(capacity int) *LRU {
+	return &LRU{
+		capacity: capacity,
+		now:      time.Now,
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}
+}
+
+// Get implements Store.
+func (l *LRU) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := el.Value.(*lruEntry)
+	if !entry.expires.IsZero() && !l.now().Before(entry.expires) {
+		l.order.Remove(el)
+		delete(l.entries, key)
+		return nil, false, nil
+	}
+	l.order.MoveToFront(el)
+	return entry.value, true, nil
+}
+
+// Set implements Store.
+func (l *LRU) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var expires time.Time
+	if ttl > 0 {
+		expires = l.now().Add(ttl)
+	}
+	if el, ok := l.entries[key]; ok {
+		el.Value = &lruEntry{key: key, value: value, expires: expires}
+		l.order.MoveToFront(el)
+		return nil
+	}
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, value: value, expires: expires})
+	for l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not yet evicted.
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
@@ -0,0 +1,41 @@
+package database
+
+import "time"
+
+// ChangeOp is the kind of write a change event reports.
+type ChangeOp string
+
+const (
+	ChangeInsert  ChangeOp = "insert"
+	ChangeUpdate  ChangeOp = "update"
+	ChangeReplace ChangeOp = "replace"
+	ChangeDelete  ChangeOp = "delete"
+	// ChangeInvalidate is sent when the watched collection is dropped or
+	// renamed, after which the stream ends
+	ChangeInvalidate ChangeOp = "invalidate"
+)
+
+// ChangeEvent is a write observed by Watch. Document holds the document as
+// it was after the write, and is nil for deletes. When Err is set the stream
+// failed and the event is the last one.
+type ChangeEvent[T any] struct {
+	Operation ChangeOp
+	Key       any
+	Document  *T
+	// UpdatedFields lists the keys set or removed by an update
+	UpdatedFields []string
+	// ResumeToken is passed to WithResumeToken to continue after this event
+	ResumeToken string
+	Time        time.Time
+	Err         error
+}
+
+// WithResumeToken makes Watch start after the event the token was taken from.
+func WithResumeToken(token string) Params {
+	return func() QueryStruct {
+		return QueryStruct{
+			key:   QueryResumeToken,
+			value: token,
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const restoreBatch = 1000
+
+type filterFlags []string
+
+func (f *filterFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *filterFlags) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// parseFilter turns key=value pairs into an equality filter. Values that parse
+// as JSON keep their type, anything else is a string, and hex strings given
+// for _id are read as object ids.
+func parseFilter(pairs []string) (bson.D, error) {
+	filter := bson.D{}
+	for _, pair := range pairs {
+		key, raw, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid filter %q, expected key=value", pair)
+		}
+		var val interface{} = raw
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(raw), &decoded); err == nil {
+			val = decoded
+		}
+		if key == "_id" {
+			if id, err := bson.ObjectIDFromHex(raw); err == nil {
+				val = id
+			}
+		}
+		filter = append(filter, bson.E{Key: key, Value: val})
+	}
+	return filter, nil
+}
+
+func (e *env) collections(ctx context.Context, args []string) error {
+	if len(args) != 1 || args[0] != "list" {
+		return errUsage
+	}
+	names, err := e.db.ListCollectionNames(ctx, bson.D{})
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		fmt.Fprintln(e.out, name)
+	}
+	return nil
+}
+
+func (e *env) count(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("count", flag.ContinueOnError)
+	var filters filterFlags
+	fs.Var(&filters, "filter", "key=value equality filter, may be repeated")
+	rest, err := parseArgs(fs, args)
+	if err != nil || len(rest) != 1 {
+		return errUsage
+	}
+	filter, err := parseFilter(filters)
+	if err != nil {
+		return err
+	}
+	count, err := e.db.Collection(rest[0]).CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(e.out, count)
+	return nil
+}
+
+// dump writes every document of a collection as canonical extended JSON, one
+// document per line, so restore can recreate the exact bson types.
+func (e *env) dump(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("dump", flag.ContinueOnError)
+	path := fs.String("out", "", "output file, defaults to stdout")
+	rest, err := parseArgs(fs, args)
+	if err != nil || len(rest) != 1 {
+		return errUsage
+	}
+
+	out := e.out
+	if *path != "" {
+		f, err := os.Create(*path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+
+	result, err := e.db.Collection(rest[0]).Find(ctx, bson.D{})
+	if err != nil {
+		return err
+	}
+	defer result.Close(ctx)
+	for result.Next(ctx) {
+		line, err := bson.MarshalExtJSON(result.Current, true, false)
+		if err != nil {
+			return err
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err := result.Err(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func (e *env) restore(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	path := fs.String("in", "", "input file, defaults to stdin")
+	rest, err := parseArgs(fs, args)
+	if err != nil || len(rest) != 1 {
+		return errUsage
+	}
+
+	var in io.Reader = os.Stdin
+	if *path != "" {
+		f, err := os.Open(*path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	col := e.db.Collection(rest[0])
+	count, err := readDocuments(in, restoreBatch, func(docs []interface{}) error {
+		_, err := col.InsertMany(ctx, docs)
+		return err
+	})
+	fmt.Fprintf(e.out, "restored %d documents\n", count)
+	return err
+}
+
+// readDocuments parses newline delimited extended JSON and hands the
+// documents to insert in batches of size.
+func readDocuments(in io.Reader, size int, insert func([]interface{}) error) (int, error) {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var total, line int
+	batch := make([]interface{}, 0, size)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := insert(batch); err != nil {
+			return err
+		}
+		total += len(batch)
+		batch = make([]interface{}, 0, size)
+		return nil
+	}
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON([]byte(text), true, &doc); err != nil {
+			return total, fmt.Errorf("line %d: %w", line, err)
+		}
+		batch = append(batch, doc)
+		if len(batch) == size {
+			if err := flush(); err != nil {
+				return total, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return total, err
+	}
+	return total, flush()
+}
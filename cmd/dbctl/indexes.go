@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/neghi-go/database"
+	"github.com/neghi-go/database/mongodb"
+)
+
+// indexFile is the on-disk index declaration read by the indexes command,
+// keyed by collection name, e.g.
+//
+//	{"users": [{"keys": [{"field": "email"}], "unique": true}]}
+type indexFile map[string][]indexEntry
+
+type indexEntry struct {
+	Name        string                 `json:"name"`
+	Keys        []indexKey             `json:"keys"`
+	Unique      bool                   `json:"unique"`
+	Sparse      bool                   `json:"sparse"`
+	ExpireAfter string                 `json:"expire_after"`
+	Weights     map[string]int32       `json:"weights"`
+	Partial     map[string]interface{} `json:"partial"`
+	Collation   *struct {
+		Locale          string `json:"locale"`
+		CaseInsensitive bool   `json:"case_insensitive"`
+		NumericOrdering bool   `json:"numeric_ordering"`
+	} `json:"collation"`
+}
+
+type indexKey struct {
+	Field string `json:"field"`
+	Kind  string `json:"kind"`
+}
+
+func (i indexEntry) spec() (database.IndexSpec, error) {
+	spec := database.IndexSpec{
+		Name:    i.Name,
+		Unique:  i.Unique,
+		Sparse:  i.Sparse,
+		Weights: i.Weights,
+		Partial: i.Partial,
+	}
+	for _, k := range i.Keys {
+		kind := database.IndexKind(k.Kind)
+		if kind == "" {
+			kind = database.IndexAsc
+		}
+		spec.Keys = append(spec.Keys, database.IndexKey{Field: k.Field, Kind: kind})
+	}
+	if i.ExpireAfter != "" {
+		d, err := time.ParseDuration(i.ExpireAfter)
+		if err != nil {
+			return spec, fmt.Errorf("invalid expire_after %q: %w", i.ExpireAfter, err)
+		}
+		spec.ExpireAfter = &d
+	}
+	if i.Collation != nil {
+		spec.Collation = &database.Collation{
+			Locale:          i.Collation.Locale,
+			CaseInsensitive: i.Collation.CaseInsensitive,
+			NumericOrdering: i.Collation.NumericOrdering,
+		}
+	}
+	return spec, nil
+}
+
+func readIndexFile(path string) (map[string][]database.IndexSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file indexFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	res := map[string][]database.IndexSpec{}
+	for coll, entries := range file {
+		for _, entry := range entries {
+			spec, err := entry.spec()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", coll, err)
+			}
+			res[coll] = append(res[coll], spec)
+		}
+	}
+	return res, nil
+}
+
+func (e *env) indexes(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	fs := flag.NewFlagSet("indexes", flag.ContinueOnError)
+	path := fs.String("spec", "", "index declaration file")
+	dryRun := fs.Bool("dry-run", false, "only print the changes")
+	rest, err := parseArgs(fs, args[1:])
+	if err != nil || len(rest) > 0 || *path == "" {
+		return errUsage
+	}
+
+	mode := mongodb.IndexDryRun
+	switch args[0] {
+	case "diff":
+	case "sync":
+		if !*dryRun {
+			mode = mongodb.IndexApply
+		}
+	default:
+		return errUsage
+	}
+
+	specs, err := readIndexFile(*path)
+	if err != nil {
+		return err
+	}
+	for _, coll := range slices.Sorted(maps.Keys(specs)) {
+		changes, err := e.reconcile(ctx, coll, specs[coll], mode)
+		if err != nil {
+			return fmt.Errorf("%s: %w", coll, err)
+		}
+		if len(changes) == 0 {
+			fmt.Fprintf(e.out, "%s: up to date\n", coll)
+		}
+		for _, c := range changes {
+			fmt.Fprintf(e.out, "%s: %s\n", coll, c)
+		}
+	}
+	return nil
+}
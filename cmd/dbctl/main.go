@@ -0,0 +1,141 @@
+// Command dbctl runs migrations, synchronises indexes and inspects data in a
+// mongo database without needing a mongo shell.
+//
+// Usage:
+//
+//	dbctl [--url URL] [--db NAME] <command> [arguments]
+//
+// The connection defaults to the DBCTL_URL and DBCTL_DB environment variables.
+// Migrations are those registered with migrate.Register, so build dbctl with
+// the package declaring them imported to manage an application's migrations.
+//
+// Commands:
+//
+//	migrate up [--to VERSION] [--dry-run]
+//	migrate down --to VERSION [--dry-run]
+//	migrate status
+//	indexes diff --spec FILE
+//	indexes sync --spec FILE [--dry-run]
+//	collections list
+//	count COLLECTION [--filter key=value]...
+//	dump COLLECTION [--out FILE]
+//	restore COLLECTION [--in FILE]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/neghi-go/database"
+	"github.com/neghi-go/database/mongodb"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const usage = `usage: dbctl [--url URL] [--db NAME] <command> [arguments]
+
+commands:
+  migrate up [--to VERSION] [--dry-run]
+  migrate down --to VERSION [--dry-run]
+  migrate status
+  indexes diff --spec FILE
+  indexes sync --spec FILE [--dry-run]
+  collections list
+  count COLLECTION [--filter key=value]...
+  dump COLLECTION [--out FILE]
+  restore COLLECTION [--in FILE]
+`
+
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "dbctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("dbctl", flag.ContinueOnError)
+	url := fs.String("url", os.Getenv("DBCTL_URL"), "mongo connection url")
+	db := fs.String("db", os.Getenv("DBCTL_DB"), "database name")
+	fs.Usage = func() {}
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	args = fs.Args()
+	if len(args) == 0 {
+		return errUsage
+	}
+	if *url == "" || *db == "" {
+		return errors.New("a connection url and database name are required, set --url and --db or DBCTL_URL and DBCTL_DB")
+	}
+
+	conn, err := mongodb.New(*url, *db)
+	if err != nil {
+		return err
+	}
+	defer conn.Disconnect(context.WithoutCancel(ctx))
+
+	e := &env{
+		db:  conn.Database(),
+		out: out,
+		reconcile: func(ctx context.Context, coll string, specs []database.IndexSpec, mode mongodb.IndexMode) ([]mongodb.IndexChange, error) {
+			return mongodb.ReconcileIndexes(ctx, conn, coll, specs, mode)
+		},
+	}
+
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "migrate":
+		return e.migrate(ctx, args)
+	case "indexes":
+		return e.indexes(ctx, args)
+	case "collections":
+		return e.collections(ctx, args)
+	case "count":
+		return e.count(ctx, args)
+	case "dump":
+		return e.dump(ctx, args)
+	case "restore":
+		return e.restore(ctx, args)
+	default:
+		return errUsage
+	}
+}
+
+// env is what the subcommands need from the connection.
+type env struct {
+	db        *mongo.Database
+	out       io.Writer
+	reconcile func(ctx context.Context, coll string, specs []database.IndexSpec, mode mongodb.IndexMode) ([]mongodb.IndexChange, error)
+}
+
+// parseArgs parses flags that may appear before or after positional arguments
+// and returns the positional ones.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, errUsage
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
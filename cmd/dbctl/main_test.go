@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neghi-go/database"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func Test_parseFilter(t *testing.T) {
+	id, _ := bson.ObjectIDFromHex("677904ef31ac7ccf730d4e39")
+	tests := []struct {
+		name    string
+		pairs   []string
+		want    bson.D
+		wantErr bool
+	}{
+		{
+			name:  "Test Typed Values",
+			pairs: []string{"name=jon", "age=20", "active=true"},
+			want: bson.D{
+				{Key: "name", Value: "jon"},
+				{Key: "age", Value: float64(20)},
+				{Key: "active", Value: true},
+			},
+		},
+		{
+			name:  "Test Object ID",
+			pairs: []string{"_id=677904ef31ac7ccf730d4e39"},
+			want:  bson.D{{Key: "_id", Value: id}},
+		},
+		{
+			name:    "Test Missing Value",
+			pairs:   []string{"name"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFilter(tt.pairs)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseFilter() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_parseArgs(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	out := fs.String("out", "", "")
+	rest, err := parseArgs(fs, []string{"users", "--out", "users.json"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"users"}, rest)
+	require.Equal(t, "users.json", *out)
+}
+
+func Test_readIndexFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "indexes.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"sessions": [
+			{"keys": [{"field": "expires_at"}], "expire_after": "24h"},
+			{"keys": [{"field": "user_id"}, {"field": "created_at", "kind": "desc"}], "unique": true}
+		]
+	}`), 0o600))
+
+	got, err := readIndexFile(path)
+	require.NoError(t, err)
+	day := 24 * time.Hour
+	require.Equal(t, map[string][]database.IndexSpec{
+		"sessions": {
+			{Keys: []database.IndexKey{{Field: "expires_at", Kind: database.IndexAsc}}, ExpireAfter: &day},
+			{
+				Keys:   []database.IndexKey{{Field: "user_id", Kind: database.IndexAsc}, {Field: "created_at", Kind: database.IndexDesc}},
+				Unique: true,
+			},
+		},
+	}, got)
+}
+
+func Test_readDocuments(t *testing.T) {
+	in := strings.NewReader(`{"_id": {"$oid": "677904ef31ac7ccf730d4e39"}, "n": {"$numberInt": "1"}}
+
+{"n": {"$numberLong": "2"}}
+{"n": 3}
+`)
+	var batches [][]interface{}
+	count, err := readDocuments(in, 2, func(docs []interface{}) error {
+		batches = append(batches, docs)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+	require.Len(t, batches, 2)
+	require.Equal(t, bson.D{{Key: "n", Value: int64(2)}}, batches[0][1])
+}
+
+func Test_run(t *testing.T) {
+	t.Setenv("DBCTL_URL", "")
+	err := run(context.Background(), []string{"collections", "list"}, io.Discard)
+	require.Error(t, err)
+
+	err = run(context.Background(), nil, io.Discard)
+	require.ErrorIs(t, err, errUsage)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/neghi-go/database/migrate"
+)
+
+func (e *env) migrate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	to := fs.Int64("to", 0, "target version")
+	dryRun := fs.Bool("dry-run", false, "only print the migrations that would run")
+	rest, err := parseArgs(fs, args[1:])
+	if err != nil || len(rest) > 0 {
+		return errUsage
+	}
+
+	var opts []migrate.Option
+	if *dryRun {
+		opts = append(opts, migrate.WithDryRun())
+	}
+	m, err := migrate.New(e.db, migrate.Registered(), opts...)
+	if err != nil {
+		return err
+	}
+
+	var done []migrate.Migration
+	switch args[0] {
+	case "up":
+		done, err = m.Up(ctx, *to)
+	case "down":
+		if !flagSet(fs, "to") {
+			return fmt.Errorf("migrate down needs an explicit --to version, use --to 0 to revert everything")
+		}
+		done, err = m.Down(ctx, *to)
+	case "status":
+		return migrateStatus(ctx, m, e.out)
+	default:
+		return errUsage
+	}
+
+	verb := "applied"
+	if args[0] == "down" {
+		verb = "reverted"
+	}
+	if *dryRun {
+		verb = "would be " + verb
+	}
+	for _, mg := range done {
+		fmt.Fprintf(e.out, "%d %s: %s\n", mg.Version, verb, mg.Description)
+	}
+	return err
+}
+
+func migrateStatus(ctx context.Context, m *migrate.Migrator, out io.Writer) error {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tSTATE\tAPPLIED AT\tDESCRIPTION")
+	for _, st := range status {
+		state, at := "pending", ""
+		if st.Applied {
+			state, at = "applied", st.AppliedAt.Format(time.RFC3339)
+		}
+		if !st.Registered {
+			state = "unknown"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", st.Version, state, at, st.Description)
+	}
+	return w.Flush()
+}
+
+func flagSet(fs *flag.FlagSet, name string) bool {
+	var set bool
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
 	"slices"
 	"strings"
@@ -34,6 +35,12 @@ type P struct {
 type M []P
 
 func EncodeModel(obj interface{}) (M, error) {
+	return encodeModel(obj, true)
+}
+
+// encodeModel encodes obj, leaving the encrypted fields in the clear unless
+// encrypt is set.
+func encodeModel(obj interface{}, encrypt bool) (M, error) {
 	var res M = M{}
 	parsed, err := parse(databaseTag, obj)
 	if err != nil {
@@ -73,19 +80,23 @@ func EncodeModel(obj interface{}) (M, error) {
 			val = p.fieldValue.Interface()
 		}
 
-		var key string
-		switch checkTag(p.fieldTag, propertyMongoID) {
-		case true:
-			key = "_id"
-		default:
-			key = getFieldname(p.fieldTag)
+		key := getFieldname(p.fieldTag)
+
+		if encrypt && checkTag(p.fieldTag, propertyEncrypted) {
+			if key == "_id" {
+				return nil, fmt.Errorf("error: id field %s can't be encrypted", p.fieldName)
+			}
+			val, err = encryptField(key, p.fieldTag, p.fieldValue)
+			if err != nil {
+				return nil, err
+			}
 		}
 
 		res = append(res, P{Key: key, Value: val,
 			Required: checkTag(p.fieldTag, propertyRequired),
 			Index:    checkTag(p.fieldTag, propertyIndex),
 			Unique:   checkTag(p.fieldTag, propertyUnique),
-			MongoID:  checkTag(p.fieldTag, propertyMongoID),
+			MongoID:  isMongoID(p.fieldTag),
 		})
 
 	}
@@ -105,40 +116,17 @@ func DecodeModel(obj interface{}, data M) error {
 				tags := strings.Split(p.Type().Field(i).Tag.Get(databaseTag), ",")
 				tag := getFieldname(tags)
 				if tag == d.Key {
-					switch field.Type() {
-					case reflect.TypeOf(uuid.UUID{}):
-						if str, ok := d.Value.(uuid.UUID); ok {
-							field.Set(reflect.ValueOf(str))
-						}
-					case reflect.TypeOf(time.Time{}):
-						if str, ok := d.Value.(time.Time); ok {
-							field.Set(reflect.ValueOf(str))
-						}
-					default:
-						switch field.Kind() {
-						case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-							val, err := handleIntTypes(d.Value)
-							if err != nil {
-								return err
-							}
-							field.Set(reflect.ValueOf(handleReflectIntKind(val, field.Kind())))
-						case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-							val, err := handleUintTypes(d.Value)
-							if err != nil {
-								return err
-							}
-							field.Set(reflect.ValueOf(handleReflectUintKind(val, field.Kind())))
-						case reflect.Float32, reflect.Float64:
-							val, err := handleFloatTypes(d.Value)
-							if err != nil {
-								return err
-							}
-							field.Set(reflect.ValueOf(handleReflectFloatKind(val, field.Kind())))
-						default:
-							field.Set(reflect.ValueOf(d.Value))
+					// values written before the field was encrypted are
+					// still read as they are
+					if checkTag(tags, propertyEncrypted) && IsEncrypted(d.Value) {
+						if err := DecryptValue(tag, d.Value.(string), field.Addr().Interface()); err != nil {
+							return err
 						}
+						continue
+					}
+					if err := decodeValue(field, d.Value); err != nil {
+						return err
 					}
-
 				}
 			}
 		}
@@ -147,8 +135,84 @@ func DecodeModel(obj interface{}, data M) error {
 	return nil
 }
 
+// decodeValue assigns value to field, converting between the numeric widths a
+// backend may hand back and the width declared on the struct.
+func decodeValue(field reflect.Value, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	switch field.Type() {
+	case reflect.TypeOf(uuid.UUID{}):
+		if str, ok := value.(uuid.UUID); ok {
+			field.Set(reflect.ValueOf(str))
+		}
+	case reflect.TypeOf(time.Time{}):
+		if str, ok := value.(time.Time); ok {
+			field.Set(reflect.ValueOf(str))
+		}
+	default:
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			val, err := handleIntTypes(value)
+			if err != nil {
+				return err
+			}
+			field.Set(reflect.ValueOf(handleReflectIntKind(val, field.Kind())))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			val, err := handleUintTypes(value)
+			if err != nil {
+				return err
+			}
+			field.Set(reflect.ValueOf(handleReflectUintKind(val, field.Kind())))
+		case reflect.Float32, reflect.Float64:
+			val, err := handleFloatTypes(value)
+			if err != nil {
+				return err
+			}
+			field.Set(reflect.ValueOf(handleReflectFloatKind(val, field.Kind())))
+		default:
+			val := reflect.ValueOf(value)
+			if !val.Type().AssignableTo(field.Type()) {
+				return fmt.Errorf("error: cannot assign value of type %s to %s", val.Type(), field.Type())
+			}
+			field.Set(val)
+		}
+	}
+	return nil
+}
+
+// DistinctAs runs Distinct on q and converts the values to V using the same
+// rules DecodeModel applies to struct fields.
+func DistinctAs[V any, T any](q Query[T], field string) ([]V, error) {
+	values, err := q.Distinct(field)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]V, 0, len(values))
+	for _, value := range values {
+		var v V
+		if err := decodeValue(reflect.ValueOf(&v).Elem(), value); err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+// checkTag reports whether the properties of a field tag, which follow the
+// field name, hold tag either bare or as tag=value.
 func checkTag(fieldTags []string, tag string) bool {
-	return slices.Contains(fieldTags, tag)
+	if len(fieldTags) == 0 {
+		return false
+	}
+	return slices.ContainsFunc(fieldTags[1:], func(t string) bool {
+		return t == tag || strings.HasPrefix(t, tag+"=")
+	})
+}
+
+// isMongoID reports whether a field tag names the mongoid field.
+func isMongoID(fieldTags []string) bool {
+	return len(fieldTags) > 0 && fieldTags[0] == propertyMongoID
 }
 
 func getFieldname(fieldTags []string) string {
@@ -145,6 +145,61 @@ func TestDecodeModel(t *testing.T) {
 	}
 }
 
+func Test_decodeValue(t *testing.T) {
+	id := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	tests := []struct {
+		name    string
+		target  interface{}
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:   "Test Int Width",
+			target: new(int8),
+			value:  int32(12),
+			want:   int8(12),
+		},
+		{
+			name:   "Test String",
+			target: new(string),
+			value:  "jon",
+			want:   "jon",
+		},
+		{
+			name:   "Test UUID",
+			target: new(uuid.UUID),
+			value:  id,
+			want:   id,
+		},
+		{
+			name:   "Test Nil Value",
+			target: new(string),
+			value:  nil,
+			want:   "",
+		},
+		{
+			name:    "Test Mismatched Type",
+			target:  new(string),
+			value:   int32(12),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field := reflect.ValueOf(tt.target).Elem()
+			err := decodeValue(field, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("decodeValue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(field.Interface(), tt.want) {
+				t.Errorf("decodeValue() = %v, want %v", field.Interface(), tt.want)
+			}
+		})
+	}
+}
+
 func Test_checkTag(t *testing.T) {
 	type args struct {
 		fieldTags []string
@@ -159,6 +214,7 @@ func Test_checkTag(t *testing.T) {
 			name: "Check Required Tag",
 			args: args{
 				fieldTags: []string{
+					"name",
 					propertyRequired,
 					propertyIndex,
 					propertyUnique,
@@ -171,6 +227,7 @@ func Test_checkTag(t *testing.T) {
 			name: "Check Index Tag",
 			args: args{
 				fieldTags: []string{
+					"name",
 					propertyRequired,
 					propertyIndex,
 					propertyUnique,
@@ -183,6 +240,7 @@ func Test_checkTag(t *testing.T) {
 			name: "Check Unique Tag",
 			args: args{
 				fieldTags: []string{
+					"name",
 					propertyRequired,
 					propertyIndex,
 					propertyUnique,
@@ -195,6 +253,7 @@ func Test_checkTag(t *testing.T) {
 			name: "Check Invalid Tag",
 			args: args{
 				fieldTags: []string{
+					"name",
 					propertyRequired,
 					propertyIndex,
 					propertyUnique,
@@ -203,6 +262,16 @@ func Test_checkTag(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "Check Field Named After Tag",
+			args: args{
+				fieldTags: []string{
+					propertyUnique,
+				},
+				tag: propertyUnique,
+			},
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
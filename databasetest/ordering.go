@@ -0,0 +1,110 @@
+// Package databasetest provides conformance checks that every database backend
+// is expected to pass.
+package databasetest
+
+import (
+	"testing"
+
+	"github.com/neghi-go/database"
+	"github.com/stretchr/testify/require"
+)
+
+// Record is the model the conformance checks run against. Score is left nil on
+// one record to exercise null placement.
+type Record struct {
+	Name  string      `db:"name"`
+	Rank  int         `db:"rank"`
+	Score interface{} `db:"score"`
+}
+
+var records = []Record{
+	{Name: "b", Rank: 1, Score: 2},
+	{Name: "a", Rank: 2},
+	{Name: "C", Rank: 1, Score: 1},
+	{Name: "d", Rank: 2, Score: 3},
+}
+
+// Ordering checks that ASC sorts smallest first and DESC largest first, that
+// multi key orders apply in precedence, and that null placement and collation
+// are honoured, by All and by Page walked both ways. The model must be backed
+// by an otherwise unused collection.
+func Ordering(t *testing.T, model database.Model[Record]) {
+	require.NoError(t, model.Query().DeleteMany())
+	require.NoError(t, model.Save(records...))
+
+	tests := []struct {
+		name   string
+		params []database.Params
+		want   []string
+	}{
+		{
+			name:   "Ascending",
+			params: []database.Params{database.WithOrder("rank", database.ASC), database.WithOrder("name", database.ASC)},
+			want:   []string{"C", "b", "a", "d"},
+		},
+		{
+			name:   "Descending",
+			params: []database.Params{database.WithOrder("rank", database.DESC), database.WithOrder("name", database.DESC)},
+			want:   []string{"d", "a", "b", "C"},
+		},
+		{
+			name:   "Multi Key",
+			params: []database.Params{database.WithOrderBy("rank", database.ASC, "name", database.DESC)},
+			want:   []string{"b", "C", "d", "a"},
+		},
+		{
+			name:   "Nulls Last",
+			params: []database.Params{database.WithOrder("score", database.ASC, database.NullsLast)},
+			want:   []string{"C", "b", "d", "a"},
+		},
+		{
+			name:   "Nulls First",
+			params: []database.Params{database.WithOrderBy("score", database.DESC, database.NullsFirst)},
+			want:   []string{"a", "d", "b", "C"},
+		},
+		{
+			name: "Case Insensitive Collation",
+			params: []database.Params{
+				database.WithOrder("name", database.ASC),
+				database.WithCollation(database.Collation{CaseInsensitive: true}),
+			},
+			want: []string{"a", "b", "C", "d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := model.Query(tt.params...).All()
+			require.NoError(t, err)
+			var got []string
+			for _, r := range res {
+				got = append(got, r.Name)
+			}
+			require.Equal(t, tt.want, got)
+
+			got = nil
+			page, err := model.Query(tt.params...).Page(1, "")
+			require.NoError(t, err)
+			for {
+				for _, r := range page.Items {
+					got = append(got, r.Name)
+				}
+				if page.NextToken == "" {
+					break
+				}
+				page, err = model.Query(tt.params...).Page(1, page.NextToken)
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.want, got, "paging forwards")
+
+			got = nil
+			for page.PrevToken != "" {
+				page, err = model.Query(tt.params...).Page(1, page.PrevToken)
+				require.NoError(t, err)
+				for _, r := range page.Items {
+					got = append([]string{r.Name}, got...)
+				}
+			}
+			require.Equal(t, tt.want[:len(tt.want)-1], got, "paging backwards")
+		})
+	}
+}
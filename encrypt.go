@@ -0,0 +1,313 @@
+package database
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	propertyEncrypted = "encrypted"
+
+	// deterministicMode is the value of encrypted= making equal values
+	// encrypt to equal ciphertexts
+	deterministicMode = "deterministic"
+
+	ciphertextPrefix = "enc:"
+)
+
+var (
+	ErrNoKeyProvider = errors.New("error: encrypted field but no key provider set")
+	ErrUnknownKey    = errors.New("error: unknown encryption key")
+)
+
+// KeyProvider supplies the AES keys of encrypted fields. Keys are 16, 24 or
+// 32 bytes long and identified by an id stored with every value, so values
+// encrypted before a rotation can still be read.
+type KeyProvider interface {
+	// CurrentKey returns the key new values are encrypted with
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key with the given id, or ErrUnknownKey
+	Key(id string) ([]byte, error)
+}
+
+var keys struct {
+	mu       sync.RWMutex
+	provider KeyProvider
+}
+
+// SetKeyProvider sets the keys used by EncodeModel and DecodeModel for the
+// fields tagged encrypted:
+//
+//	SSN   string `db:"ssn,encrypted"`
+//	Email string `db:"email,encrypted=deterministic,unique"`
+//
+// Values are encrypted with AES-GCM and stored as strings. The deterministic
+// mode derives the nonce from the value, so equal values give equal
+// ciphertexts under the same key and the field can be used in equality
+// filters and unique indexes, at the cost of revealing which documents share
+// a value. Lookups only find values written with the current key, so
+// deterministic fields must be rewritten after a rotation.
+//
+// Documents are decrypted when decoded, so caches of query results hold them
+// in the clear.
+func SetKeyProvider(p KeyProvider) {
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+	keys.provider = p
+}
+
+func keyProvider() (KeyProvider, error) {
+	keys.mu.RLock()
+	defer keys.mu.RUnlock()
+	if keys.provider == nil {
+		return nil, ErrNoKeyProvider
+	}
+	return keys.provider, nil
+}
+
+// StaticKeys is a KeyProvider holding its keys in memory, for keys loaded from
+// the environment or a secret store at startup.
+type StaticKeys struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewStaticKeys returns a provider encrypting with the key current and
+// decrypting with any of keys.
+func NewStaticKeys(current string, keys map[string][]byte) (*StaticKeys, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("error: current key %q is not among the keys", current)
+	}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("error: invalid key id %q", id)
+		}
+		if _, err := aes.NewCipher(key); err != nil {
+			return nil, fmt.Errorf("error: key %q: %w", id, err)
+		}
+	}
+	return &StaticKeys{current: current, keys: keys}, nil
+}
+
+// CurrentKey implements KeyProvider.
+func (s *StaticKeys) CurrentKey() (string, []byte, error) {
+	return s.current, s.keys[s.current], nil
+}
+
+// Key implements KeyProvider.
+func (s *StaticKeys) Key(id string) ([]byte, error) {
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// EncryptValue encrypts value as stored in the field key, the way EncodeModel
+// does for fields tagged encrypted.
+func EncryptValue(key string, value interface{}, deterministic bool) (string, error) {
+	provider, err := keyProvider()
+	if err != nil {
+		return "", err
+	}
+	id, secret, err := provider.CurrentKey()
+	if err != nil {
+		return "", err
+	}
+	if id == "" || strings.Contains(id, ":") {
+		return "", fmt.Errorf("error: invalid key id %q", id)
+	}
+	aead, err := newAEAD(secret)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if deterministic {
+		// a synthetic nonce, as in SIV modes: it only repeats for the same
+		// value of the same field, which then yields the same ciphertext
+		mac := hmac.New(sha256.New, nonceKey(secret))
+		mac.Write([]byte(key))
+		mac.Write([]byte{0})
+		mac.Write(plaintext)
+		copy(nonce, mac.Sum(nil))
+	} else if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	// the field key is authenticated so a value can't be moved to another
+	// field of the same document
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(key))
+	return ciphertextPrefix + id + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptValue decrypts a value of the field key into dst, a pointer.
+func DecryptValue(key string, ciphertext string, dst interface{}) error {
+	rest, ok := strings.CutPrefix(ciphertext, ciphertextPrefix)
+	if !ok {
+		return errors.New("error: value is not encrypted")
+	}
+	id, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return errors.New("error: malformed encrypted value")
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("error: malformed encrypted value: %w", err)
+	}
+	provider, err := keyProvider()
+	if err != nil {
+		return err
+	}
+	secret, err := provider.Key(id)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(secret)
+	if err != nil {
+		return err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return errors.New("error: malformed encrypted value")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(key))
+	if err != nil {
+		return fmt.Errorf("error: decrypting %s: %w", key, err)
+	}
+	return json.Unmarshal(plaintext, dst)
+}
+
+// IsEncrypted reports whether value looks like a value written by
+// EncryptValue.
+func IsEncrypted(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && strings.HasPrefix(s, ciphertextPrefix)
+}
+
+// FilterValue returns the value to compare the field key against in a
+// filter, encrypted when the field is deterministically encrypted. Operator
+// filters, given as a map or a key/value document such as bson.M or bson.D,
+// have the operands of $eq, $ne, $in and $nin encrypted one by one; other
+// operators would compare ciphertexts and are rejected. Randomly encrypted
+// fields can't be filtered on.
+func FilterValue(fields []Field, key string, value interface{}) (interface{}, error) {
+	f, ok := LookupField(fields, key)
+	if !ok || !f.Encrypted || key != f.Key {
+		return value, nil
+	}
+	if !f.Deterministic {
+		return nil, fmt.Errorf("error: field %s is encrypted and can't be filtered on", key)
+	}
+	if res, ok, err := encryptOperators(key, value); ok {
+		return res, err
+	}
+	return EncryptValue(key, value, true)
+}
+
+// encryptOperators encrypts the operands of an operator filter on key,
+// keeping the type of the filter. It reports false when value isn't an
+// operator filter.
+func encryptOperators(key string, value interface{}) (interface{}, bool, error) {
+	rv := reflect.ValueOf(value)
+	switch {
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String &&
+		rv.Type().Elem().Kind() == reflect.Interface:
+		if rv.Len() == 0 {
+			return nil, false, nil
+		}
+		res := reflect.MakeMapWithSize(rv.Type(), rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			op := iter.Key().String()
+			if !strings.HasPrefix(op, "$") {
+				return nil, false, nil
+			}
+			operand, err := encryptOperand(key, op, iter.Value().Interface())
+			if err != nil {
+				return nil, true, err
+			}
+			res.SetMapIndex(iter.Key(), reflect.ValueOf(operand))
+		}
+		return res.Interface(), true, nil
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Struct:
+		elem := rv.Type().Elem()
+		k, ok := elem.FieldByName("Key")
+		v, vok := elem.FieldByName("Value")
+		if !ok || !vok || k.Type.Kind() != reflect.String || v.Type.Kind() != reflect.Interface || rv.Len() == 0 {
+			return nil, false, nil
+		}
+		res := reflect.MakeSlice(rv.Type(), rv.Len(), rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			op := rv.Index(i).FieldByIndex(k.Index).String()
+			if !strings.HasPrefix(op, "$") {
+				return nil, false, nil
+			}
+			operand, err := encryptOperand(key, op, rv.Index(i).FieldByIndex(v.Index).Interface())
+			if err != nil {
+				return nil, true, err
+			}
+			res.Index(i).Set(rv.Index(i))
+			res.Index(i).FieldByIndex(v.Index).Set(reflect.ValueOf(operand))
+		}
+		return res.Interface(), true, nil
+	}
+	return nil, false, nil
+}
+
+// encryptOperand encrypts the operand of the filter operator op on key.
+func encryptOperand(key, op string, operand interface{}) (interface{}, error) {
+	switch op {
+	case "$eq", "$ne":
+		return EncryptValue(key, operand, true)
+	case "$in", "$nin":
+		rv := reflect.ValueOf(operand)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return nil, fmt.Errorf("error: %s on field %s expects a list, got %T", op, key, operand)
+		}
+		res := make([]interface{}, rv.Len())
+		for i := range res {
+			val, err := EncryptValue(key, rv.Index(i).Interface(), true)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = val
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("error: operator %s can't be used on encrypted field %s", op, key)
+}
+
+func newAEAD(secret []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// nonceKey derives the key of the synthetic nonces, keeping it apart from the
+// encryption key.
+func nonceKey(secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("database field nonce"))
+	return mac.Sum(nil)
+}
+
+// encryptField encrypts the value of a struct field tagged encrypted.
+func encryptField(key string, tags []string, value reflect.Value) (interface{}, error) {
+	mode, _ := tagValue(tags, propertyEncrypted)
+	return EncryptValue(key, value.Interface(), mode == deterministicMode)
+}
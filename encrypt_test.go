@@ -0,0 +1,156 @@
+package database
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type patient struct {
+	ID    string   `db:"mongoid"`
+	Name  string   `db:"name"`
+	SSN   string   `db:"ssn,encrypted"`
+	Email string   `db:"email,encrypted=deterministic"`
+	Age   int      `db:"age,encrypted"`
+	Tags  []string `db:"tags,encrypted"`
+}
+
+func useKeys(t *testing.T, current string, keys map[string][]byte) {
+	p, err := NewStaticKeys(current, keys)
+	require.NoError(t, err)
+	SetKeyProvider(p)
+	t.Cleanup(func() { SetKeyProvider(nil) })
+}
+
+func valueOf(m M, key string) interface{} {
+	for _, p := range m {
+		if p.Key == key {
+			return p.Value
+		}
+	}
+	return nil
+}
+
+func TestNewStaticKeys(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	_, err := NewStaticKeys("k1", map[string][]byte{"k1": key})
+	require.NoError(t, err)
+
+	_, err = NewStaticKeys("k2", map[string][]byte{"k1": key})
+	require.Error(t, err, "current key missing")
+	_, err = NewStaticKeys("k1", map[string][]byte{"k1": key[:10]})
+	require.Error(t, err, "invalid key size")
+	_, err = NewStaticKeys("k:1", map[string][]byte{"k:1": key})
+	require.Error(t, err, "the id separator in an id")
+}
+
+func TestEncryptedFields(t *testing.T) {
+	k1, k2 := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 16)
+	doc := patient{Name: "Ann", SSN: "123-45-6789", Email: "ann@example.com", Age: 42, Tags: []string{"vip"}}
+
+	_, err := EncodeModel(doc)
+	require.ErrorIs(t, err, ErrNoKeyProvider)
+
+	useKeys(t, "k1", map[string][]byte{"k1": k1})
+	enc, err := EncodeModel(doc)
+	require.NoError(t, err)
+	require.Equal(t, "Ann", valueOf(enc, "name"))
+	for _, key := range []string{"ssn", "email", "age", "tags"} {
+		require.True(t, IsEncrypted(valueOf(enc, key)), key)
+	}
+
+	again, err := EncodeModel(doc)
+	require.NoError(t, err)
+	require.NotEqual(t, valueOf(enc, "ssn"), valueOf(again, "ssn"), "random mode uses a fresh nonce")
+	require.Equal(t, valueOf(enc, "email"), valueOf(again, "email"), "deterministic mode repeats")
+
+	var got patient
+	require.NoError(t, DecodeModel(&got, enc))
+	require.Equal(t, doc, got)
+
+	// values written with a retired key are read after a rotation
+	useKeys(t, "k2", map[string][]byte{"k1": k1, "k2": k2})
+	got = patient{}
+	require.NoError(t, DecodeModel(&got, enc))
+	require.Equal(t, doc, got)
+	rotated, err := EncodeModel(doc)
+	require.NoError(t, err)
+	require.NotEqual(t, valueOf(enc, "email"), valueOf(rotated, "email"))
+
+	useKeys(t, "k2", map[string][]byte{"k2": k2})
+	require.ErrorIs(t, DecodeModel(&got, enc), ErrUnknownKey)
+
+	// a value moved to another field fails authentication
+	require.Error(t, DecodeModel(&got, M{{Key: "ssn", Value: valueOf(rotated, "email")}}))
+
+	// plaintext written before the field was encrypted is still read
+	got = patient{}
+	require.NoError(t, DecodeModel(&got, M{{Key: "ssn", Value: "987-65-4321"}}))
+	require.Equal(t, "987-65-4321", got.SSN)
+}
+
+func TestFilterValue(t *testing.T) {
+	useKeys(t, "k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	fields, err := FieldsOf(patient{})
+	require.NoError(t, err)
+	enc, err := EncodeModel(patient{Email: "ann@example.com"})
+	require.NoError(t, err)
+
+	got, err := FilterValue(fields, "email", "ann@example.com")
+	require.NoError(t, err)
+	require.Equal(t, valueOf(enc, "email"), got)
+
+	got, err = FilterValue(fields, "name", "Ann")
+	require.NoError(t, err)
+	require.Equal(t, "Ann", got)
+
+	_, err = FilterValue(fields, "ssn", "123-45-6789")
+	require.Error(t, err)
+
+	bob, err := EncodeModel(patient{Email: "bob@example.com"})
+	require.NoError(t, err)
+	got, err = FilterValue(fields, "email", map[string]interface{}{"$in": []string{"ann@example.com", "bob@example.com"}})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"$in": []interface{}{valueOf(enc, "email"), valueOf(bob, "email")}}, got)
+
+	got, err = FilterValue(fields, "email", M{{Key: "$ne", Value: "ann@example.com"}})
+	require.NoError(t, err)
+	require.Equal(t, M{{Key: "$ne", Value: valueOf(enc, "email")}}, got)
+
+	_, err = FilterValue(fields, "email", map[string]interface{}{"$gt": "ann@example.com"})
+	require.Error(t, err)
+
+	_, err = FilterValue(fields, "email", map[string]interface{}{"$in": "ann@example.com"})
+	require.Error(t, err)
+}
+
+func TestEncryptedTags(t *testing.T) {
+	_, err := FieldsOf(struct {
+		SSN string `db:"ssn,encrypted=aes"`
+	}{})
+	require.Error(t, err)
+
+	_, err = FieldsOf(struct {
+		ID string `db:"mongoid,encrypted"`
+	}{})
+	require.Error(t, err)
+
+	fields, err := FieldsOf(patient{})
+	require.NoError(t, err)
+	email, _ := LookupField(fields, "email")
+	require.True(t, email.Encrypted)
+	require.True(t, email.Deterministic)
+
+	// a field named encrypted holds plaintext
+	fields, err = FieldsOf(struct {
+		Encrypted bool `db:"encrypted"`
+	}{})
+	require.NoError(t, err)
+	require.False(t, fields[0].Encrypted)
+	enc, err := EncodeModel(struct {
+		Encrypted bool `db:"encrypted"`
+	}{Encrypted: true})
+	require.NoError(t, err)
+	require.Equal(t, true, valueOf(enc, "encrypted"))
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	propertyEnum = "enum"
+	propertyMin  = "min"
+	propertyMax  = "max"
+	propertyRef  = "ref"
+)
+
+// Field describes a db tagged struct field together with the validation rules
+// declared on it. Min and Max bound numeric values, or the length of strings
+// and slices.
+type Field struct {
+	Name     string
+	Key      string
+	Type     reflect.Type
+	Required bool
+	MongoID  bool
+	Enum     []string
+	Min      *float64
+	Max      *float64
+	// Ref is the collection the value of the field refers to, declared
+	// with ref=<collection>
+	Ref string
+	// Tenant marks the field holding the tenant a document belongs to
+	Tenant bool
+	// Encrypted fields are stored encrypted, deterministically when
+	// Deterministic is set
+	Encrypted     bool
+	Deterministic bool
+}
+
+// FieldsOf describes the fields of a model in declaration order. Besides the
+// storage properties it reads enum=a|b|c, min=<n>, max=<n>, ref=<collection>,
+// encrypted[=deterministic] and the tenant flag.
+func FieldsOf(model interface{}) ([]Field, error) {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf(ErrNotStruct.Error(), reflect.Struct.String(), reflect.ValueOf(model).Kind().String())
+	}
+
+	var res []Field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		def, ok := sf.Tag.Lookup(databaseTag)
+		if !ok {
+			return nil, fmt.Errorf("error: struct tag expected on field %s, got empty", sf.Name)
+		}
+		tags := strings.Split(def, ",")
+		if tags[0] == skipFieldTag {
+			continue
+		}
+		f := Field{
+			Name:     sf.Name,
+			Key:      getFieldname(tags),
+			Type:     sf.Type,
+			Required: checkTag(tags, propertyRequired),
+			MongoID:  isMongoID(tags),
+			Tenant:   checkTag(tags, propertyTenant),
+		}
+		if mode, ok := tagValue(tags, propertyEncrypted); ok {
+			if mode != "" && mode != deterministicMode || f.MongoID {
+				return nil, fmt.Errorf("error: invalid encryption %q on field %s", mode, sf.Name)
+			}
+			f.Encrypted, f.Deterministic = true, mode == deterministicMode
+		}
+		if val, ok := tagValue(tags, propertyRef); ok {
+			f.Ref = val
+		}
+		if val, ok := tagValue(tags, propertyEnum); ok && val != "" {
+			f.Enum = strings.Split(val, "|")
+		}
+		for prop, dst := range map[string]**float64{propertyMin: &f.Min, propertyMax: &f.Max} {
+			if val, ok := tagValue(tags, prop); ok {
+				n, err := strconv.ParseFloat(val, 64)
+				if err != nil {
+					return nil, fmt.Errorf("error: invalid %s %q on field %s", prop, val, sf.Name)
+				}
+				*dst = &n
+			}
+		}
+		res = append(res, f)
+	}
+	return res, nil
+}
+
+// EnumValues returns the enum values converted to the field's kind, so an enum
+// on an integer field compares against numbers rather than strings.
+func (f Field) EnumValues() ([]interface{}, error) {
+	t := f.Type
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var res []interface{}
+	for _, e := range f.Enum {
+		var val interface{}
+		var err error
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			val, err = strconv.ParseInt(e, 10, 64)
+		case reflect.Float32, reflect.Float64:
+			val, err = strconv.ParseFloat(e, 64)
+		case reflect.Bool:
+			val, err = strconv.ParseBool(e)
+		default:
+			val = e
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error: invalid enum value %q on field %s", e, f.Name)
+		}
+		res = append(res, val)
+	}
+	return res, nil
+}
+
+// LookupField finds the field a key path such as "address.city" starts with.
+// Nested keys are not checked, as they may belong to maps or sub-documents.
+func LookupField(fields []Field, path string) (Field, bool) {
+	key, _, _ := strings.Cut(path, ".")
+	for _, f := range fields {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldsOf(t *testing.T) {
+	one, ten := 1.0, 10.0
+	type user struct {
+		ID     string   `db:"mongoid"`
+		Name   string   `db:"name,required,min=1,max=10"`
+		Role   string   `db:"role,enum=admin|read-only"`
+		Tags   []string `db:"tags"`
+		TeamID string   `db:"team_id,ref=teams"`
+		Secret string   `db:"-"`
+	}
+	tests := []struct {
+		name    string
+		model   interface{}
+		want    []Field
+		wantErr bool
+	}{
+		{
+			name:  "Test Valid Model",
+			model: &user{},
+			want: []Field{
+				{Name: "ID", Key: "_id", Type: reflect.TypeOf(""), MongoID: true},
+				{Name: "Name", Key: "name", Type: reflect.TypeOf(""), Required: true, Min: &one, Max: &ten},
+				{Name: "Role", Key: "role", Type: reflect.TypeOf(""), Enum: []string{"admin", "read-only"}},
+				{Name: "Tags", Key: "tags", Type: reflect.TypeOf([]string{})},
+				{Name: "TeamID", Key: "team_id", Type: reflect.TypeOf(""), Ref: "teams"},
+			},
+		},
+		{
+			name: "Test Fields Named After Properties",
+			model: struct {
+				Min  int    `db:"min"`
+				Max  int    `db:"max,min=0"`
+				Enum string `db:"enum"`
+				Ref  string `db:"ref"`
+			}{},
+			want: []Field{
+				{Name: "Min", Key: "min", Type: reflect.TypeOf(0)},
+				{Name: "Max", Key: "max", Type: reflect.TypeOf(0), Min: new(float64)},
+				{Name: "Enum", Key: "enum", Type: reflect.TypeOf("")},
+				{Name: "Ref", Key: "ref", Type: reflect.TypeOf("")},
+			},
+		},
+		{
+			name: "Test Invalid Bound",
+			model: struct {
+				Age int `db:"age,min=zero"`
+			}{},
+			wantErr: true,
+		},
+		{
+			name: "Test Missing Tag",
+			model: struct {
+				Age int
+			}{},
+			wantErr: true,
+		},
+		{
+			name:    "Test Not A Struct",
+			model:   "user",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FieldsOf(tt.model)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FieldsOf() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLookupField(t *testing.T) {
+	fields, err := FieldsOf(struct {
+		ID      string            `db:"mongoid"`
+		Name    string            `db:"name"`
+		Address map[string]string `db:"address"`
+	}{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{path: "name", want: "Name", ok: true},
+		{path: "_id", want: "ID", ok: true},
+		{path: "address.city", want: "Address", ok: true},
+		{path: "nickname"},
+		{path: "names"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			f, ok := LookupField(fields, tt.path)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.want, f.Name)
+		})
+	}
+}
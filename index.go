@@ -0,0 +1,166 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	propertyText   = "text"
+	propertyWeight = "weight"
+	propertyHashed = "hashed"
+	propertySparse = "sparse"
+	propertyExpire = "expire"
+)
+
+// IndexKind is how a single key of an index is ordered or hashed.
+type IndexKind string
+
+const (
+	IndexAsc    IndexKind = "asc"
+	IndexDesc   IndexKind = "desc"
+	IndexText   IndexKind = "text"
+	IndexHashed IndexKind = "hashed"
+)
+
+// IndexKey is one field of an index. Compound indexes list their keys in order
+// of precedence.
+type IndexKey struct {
+	Field string
+	Kind  IndexKind
+}
+
+// IndexSpec is a backend neutral index declaration. Backends translate it into
+// their own index definition, e.g. createIndexes on Mongo or CREATE INDEX on a
+// SQL database; options a backend cannot honour are an error there.
+type IndexSpec struct {
+	// Name overrides the name the backend would derive from the keys
+	Name string
+	Keys []IndexKey
+	// Unique rejects documents that repeat the indexed values
+	Unique bool
+	// Sparse leaves out documents that do not have the indexed fields
+	Sparse bool
+	// ExpireAfter removes documents once the indexed time is older than it.
+	// Zero removes them at the time the field holds, nil makes no TTL index
+	ExpireAfter *time.Duration
+	// Weights sets the relevance of each field of a text index
+	Weights map[string]int32
+	// Partial limits the index to documents matching the filter
+	Partial map[string]interface{}
+	// Collation makes string keys compare with the given collation
+	Collation *Collation
+}
+
+// Indexer is implemented by models that declare indexes which cannot be
+// expressed with field tags, such as compound or partial indexes.
+type Indexer interface {
+	Indexes() []IndexSpec
+}
+
+// IndexesOf returns the indexes declared by a model, first those from field
+// tags and then those returned by its Indexes method if it is an Indexer.
+//
+// The supported tag properties are index (or index=desc), unique, sparse,
+// hashed, expire=<duration> for TTL indexes, and text with an optional
+// weight=<n>. All text fields are combined into a single text index.
+func IndexesOf(model interface{}) ([]IndexSpec, error) {
+	parsed, err := parse(databaseTag, model)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []IndexSpec
+	var text *IndexSpec
+	for _, p := range parsed {
+		key := getFieldname(p.fieldTag)
+		if checkTag(p.fieldTag, propertyText) {
+			if text == nil {
+				text = &IndexSpec{}
+			}
+			text.Keys = append(text.Keys, IndexKey{Field: key, Kind: IndexText})
+			if val, ok := tagValue(p.fieldTag, propertyWeight); ok {
+				weight, err := strconv.ParseInt(val, 10, 32)
+				if err != nil {
+					return nil, fmt.Errorf("error: invalid weight %q on field %s", val, p.fieldName)
+				}
+				if text.Weights == nil {
+					text.Weights = map[string]int32{}
+				}
+				text.Weights[key] = int32(weight)
+			}
+			continue
+		}
+
+		if !checkTag(p.fieldTag, propertyIndex) && !checkTag(p.fieldTag, propertyHashed) &&
+			!checkTag(p.fieldTag, propertyExpire) {
+			continue
+		}
+		if isMongoID(p.fieldTag) {
+			return nil, errors.New("setting mongoid already sets index")
+		}
+
+		kind := IndexAsc
+		switch val, _ := tagValue(p.fieldTag, propertyIndex); val {
+		case "", "asc":
+		case "desc":
+			kind = IndexDesc
+		default:
+			return nil, fmt.Errorf("error: invalid index direction %q on field %s", val, p.fieldName)
+		}
+		if checkTag(p.fieldTag, propertyHashed) {
+			kind = IndexHashed
+		}
+
+		spec := IndexSpec{
+			Keys:   []IndexKey{{Field: key, Kind: kind}},
+			Unique: checkTag(p.fieldTag, propertyUnique),
+			Sparse: checkTag(p.fieldTag, propertySparse),
+		}
+		if val, ok := tagValue(p.fieldTag, propertyExpire); ok {
+			expire, err := time.ParseDuration(val)
+			if err != nil {
+				return nil, fmt.Errorf("error: invalid expiry %q on field %s", val, p.fieldName)
+			}
+			spec.ExpireAfter = &expire
+		}
+		res = append(res, spec)
+	}
+	if text != nil {
+		res = append(res, *text)
+	}
+
+	// Indexes may be declared on the pointer receiver
+	if indexer, ok := model.(Indexer); ok {
+		res = append(res, indexer.Indexes()...)
+	} else if v := reflect.ValueOf(model); v.Kind() != reflect.Pointer {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		if indexer, ok := ptr.Interface().(Indexer); ok {
+			res = append(res, indexer.Indexes()...)
+		}
+	}
+	return res, nil
+}
+
+// tagValue returns the value of a name=value tag property. A bare name is
+// reported as present with an empty value. The field name leading the tag is
+// never read as a property.
+func tagValue(fieldTags []string, name string) (string, bool) {
+	if len(fieldTags) == 0 {
+		return "", false
+	}
+	for _, tag := range fieldTags[1:] {
+		if tag == name {
+			return "", true
+		}
+		if val, ok := strings.CutPrefix(tag, name+"="); ok {
+			return val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type indexedModel struct {
+	Email     string    `db:"email,index,unique"`
+	Org       string    `db:"org"`
+	CreatedAt time.Time `db:"created_at,index=desc"`
+}
+
+func (indexedModel) Indexes() []IndexSpec {
+	return []IndexSpec{
+		{
+			Keys:    []IndexKey{{Field: "org", Kind: IndexAsc}, {Field: "created_at", Kind: IndexDesc}},
+			Partial: map[string]interface{}{"org": map[string]interface{}{"$exists": true}},
+		},
+	}
+}
+
+type pointerIndexedModel struct {
+	Org string `db:"org"`
+}
+
+func (*pointerIndexedModel) Indexes() []IndexSpec {
+	return []IndexSpec{{Keys: []IndexKey{{Field: "org", Kind: IndexHashed}}}}
+}
+
+func duration(d time.Duration) *time.Duration {
+	return &d
+}
+
+func TestIndexesOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		model   interface{}
+		want    []IndexSpec
+		wantErr bool
+	}{
+		{
+			name: "Test Tag Indexes",
+			model: struct {
+				Email   string    `db:"email,index,unique,sparse"`
+				Session string    `db:"session,hashed"`
+				Expires time.Time `db:"expires,expire=24h"`
+				Name    string    `db:"name"`
+			}{},
+			want: []IndexSpec{
+				{Keys: []IndexKey{{Field: "email", Kind: IndexAsc}}, Unique: true, Sparse: true},
+				{Keys: []IndexKey{{Field: "session", Kind: IndexHashed}}},
+				{Keys: []IndexKey{{Field: "expires", Kind: IndexAsc}}, ExpireAfter: duration(24 * time.Hour)},
+			},
+		},
+		{
+			name: "Test Text Index",
+			model: struct {
+				Title string `db:"title,text,weight=10"`
+				Body  string `db:"body,text"`
+			}{},
+			want: []IndexSpec{
+				{
+					Keys:    []IndexKey{{Field: "title", Kind: IndexText}, {Field: "body", Kind: IndexText}},
+					Weights: map[string]int32{"title": 10},
+				},
+			},
+		},
+		{
+			name: "Test Fields Named After Properties",
+			model: struct {
+				Text   string    `db:"text"`
+				Weight int       `db:"weight"`
+				Expire time.Time `db:"expire,index"`
+			}{},
+			want: []IndexSpec{
+				{Keys: []IndexKey{{Field: "expire", Kind: IndexAsc}}},
+			},
+		},
+		{
+			name:  "Test Indexer Model",
+			model: indexedModel{},
+			want: []IndexSpec{
+				{Keys: []IndexKey{{Field: "email", Kind: IndexAsc}}, Unique: true},
+				{Keys: []IndexKey{{Field: "created_at", Kind: IndexDesc}}},
+				{
+					Keys:    []IndexKey{{Field: "org", Kind: IndexAsc}, {Field: "created_at", Kind: IndexDesc}},
+					Partial: map[string]interface{}{"org": map[string]interface{}{"$exists": true}},
+				},
+			},
+		},
+		{
+			name:  "Test Pointer Indexer Model",
+			model: pointerIndexedModel{},
+			want:  []IndexSpec{{Keys: []IndexKey{{Field: "org", Kind: IndexHashed}}}},
+		},
+		{
+			name: "Test Expire At Field Time",
+			model: struct {
+				Expires time.Time `db:"expires,expire=0s"`
+			}{},
+			want: []IndexSpec{
+				{Keys: []IndexKey{{Field: "expires", Kind: IndexAsc}}, ExpireAfter: duration(0)},
+			},
+		},
+		{
+			name: "Test Invalid Expiry",
+			model: struct {
+				Expires time.Time `db:"expires,expire=soon"`
+			}{},
+			wantErr: true,
+		},
+		{
+			name: "Test Invalid Direction",
+			model: struct {
+				Name string `db:"name,index=up"`
+			}{},
+			wantErr: true,
+		},
+		{
+			name: "Test MongoID Index",
+			model: struct {
+				ID string `db:"mongoid,index"`
+			}{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IndexesOf(tt.model)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("IndexesOf() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_tagValue(t *testing.T) {
+	tags := []string{"created_at", "index", "expire=24h"}
+
+	val, ok := tagValue(tags, "expire")
+	require.True(t, ok)
+	require.Equal(t, "24h", val)
+
+	val, ok = tagValue(tags, "index")
+	require.True(t, ok)
+	require.Empty(t, val)
+
+	_, ok = tagValue(tags, "weight")
+	require.False(t, ok)
+
+	_, ok = tagValue([]string{"expire"}, "expire")
+	require.False(t, ok)
+}
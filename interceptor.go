@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// Operation names reported in OpInfo.Operation.
+const (
+	OpFind       = "find"
+	OpFindOne    = "find_one"
+	OpCount      = "count"
+	OpDistinct   = "distinct"
+	OpAggregate  = "aggregate"
+	OpInsert     = "insert"
+	OpUpdate     = "update"
+	OpUpdateMany = "update_many"
+	OpDelete     = "delete"
+	OpDeleteMany = "delete_many"
+	OpExplain    = "explain"
+	OpWatch      = "watch"
+)
+
+// OpInfo describes a single operation sent to the database. Duration and Err
+// are filled in once the operation has run, so an interceptor can read them
+// after its call to next returns.
+type OpInfo struct {
+	System     string
+	Database   string
+	Collection string
+	Operation  string
+	Filter     interface{}
+	Sort       interface{}
+	Limit      int64
+	Offset     int64
+	// Query is a summary of the filter with every literal value replaced by
+	// "?", safe to log or attach to traces.
+	Query string
+
+	// Docs is the number of documents returned by a read, or matched,
+	// inserted or deleted by a write.
+	Docs     int64
+	Duration time.Duration
+	Err      error
+}
+
+// Interceptor observes or guards an operation. It must call next to run the
+// operation, unless it wants to reject it by returning an error instead. The
+// context given to next is the one the operation runs with, so an interceptor
+// can pass on a derived one, such as a context holding a span.
+type Interceptor func(ctx context.Context, op *OpInfo, next func(context.Context) error) error
+
+// Intercept runs fn through the chain of interceptors, the first one being the
+// outermost, and records the duration and error of fn on op. fn receives the
+// context passed down by the innermost interceptor.
+func Intercept(ctx context.Context, chain []Interceptor, op *OpInfo, fn func(context.Context) error) error {
+	next := func(ctx context.Context) error {
+		start := time.Now()
+		err := fn(ctx)
+		op.Duration, op.Err = time.Since(start), err
+		return err
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		interceptor, inner := chain[i], next
+		next = func(ctx context.Context) error {
+			return interceptor(ctx, op, inner)
+		}
+	}
+	return next(ctx)
+}
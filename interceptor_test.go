@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntercept(t *testing.T) {
+	errDenied := errors.New("denied")
+	errFailed := errors.New("failed")
+
+	record := func(name string, calls *[]string) Interceptor {
+		return func(ctx context.Context, op *OpInfo, next func(context.Context) error) error {
+			*calls = append(*calls, name+" before")
+			err := next(ctx)
+			*calls = append(*calls, name+" after")
+			return err
+		}
+	}
+
+	tests := []struct {
+		name      string
+		chain     func(calls *[]string) []Interceptor
+		fn        error
+		wantCalls []string
+		wantErr   error
+		wantRan   bool
+	}{
+		{
+			name:      "Test Empty Chain",
+			chain:     func(calls *[]string) []Interceptor { return nil },
+			wantCalls: []string{"op"},
+			wantRan:   true,
+		},
+		{
+			name: "Test Chain Order",
+			chain: func(calls *[]string) []Interceptor {
+				return []Interceptor{record("outer", calls), record("inner", calls)}
+			},
+			wantCalls: []string{"outer before", "inner before", "op", "inner after", "outer after"},
+			wantRan:   true,
+		},
+		{
+			name: "Test Operation Error",
+			chain: func(calls *[]string) []Interceptor {
+				return []Interceptor{record("outer", calls)}
+			},
+			fn:        errFailed,
+			wantCalls: []string{"outer before", "op", "outer after"},
+			wantErr:   errFailed,
+			wantRan:   true,
+		},
+		{
+			name: "Test Rejected",
+			chain: func(calls *[]string) []Interceptor {
+				return []Interceptor{
+					record("outer", calls),
+					func(ctx context.Context, op *OpInfo, next func(context.Context) error) error { return errDenied },
+					record("inner", calls),
+				}
+			},
+			wantCalls: []string{"outer before", "outer after"},
+			wantErr:   errDenied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			op := &OpInfo{Operation: OpFind}
+			err := Intercept(context.Background(), tt.chain(&calls), op, func(context.Context) error {
+				calls = append(calls, "op")
+				return tt.fn
+			})
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Equal(t, tt.wantCalls, calls)
+			if tt.wantRan {
+				require.Equal(t, tt.fn, op.Err)
+			} else {
+				require.Equal(t, OpInfo{Operation: OpFind}, *op)
+			}
+		})
+	}
+
+	t.Run("Test Derived Context", func(t *testing.T) {
+		type key struct{}
+		chain := []Interceptor{func(ctx context.Context, op *OpInfo, next func(context.Context) error) error {
+			return next(context.WithValue(ctx, key{}, "span"))
+		}}
+		var got any
+		err := Intercept(context.Background(), chain, &OpInfo{}, func(ctx context.Context) error {
+			got = ctx.Value(key{})
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, "span", got)
+	})
+}
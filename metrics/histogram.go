@@ -0,0 +1,45 @@
+package metrics
+
+import "slices"
+
+type histogram struct {
+	bounds []float64
+	// counts holds one bucket per bound plus the +Inf bucket, not cumulative
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	i, _ := slices.BinarySearch(h.bounds, v)
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+// Bucket is a cumulative histogram bucket, counting observations less than or
+// equal to UpperBound.
+type Bucket struct {
+	UpperBound float64 `json:"le"`
+	Count      uint64  `json:"count"`
+}
+
+type HistogramSnapshot struct {
+	Buckets []Bucket `json:"buckets"`
+	Sum     float64  `json:"sum"`
+	Count   uint64   `json:"count"`
+}
+
+func (h *histogram) snapshot() HistogramSnapshot {
+	res := HistogramSnapshot{Buckets: make([]Bucket, 0, len(h.bounds)), Sum: h.sum, Count: h.count}
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		res.Buckets = append(res.Buckets, Bucket{UpperBound: bound, Count: cumulative})
+	}
+	return res
+}
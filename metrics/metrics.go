@@ -0,0 +1,278 @@
+// Package metrics records latency, error and document counts of model
+// operations along with connection pool gauges, and exposes them in the
+// Prometheus text format or through expvar.
+//
+//	m := metrics.New()
+//	conn, err := mongodb.New(url, "app", mongodb.WithPoolMonitor(m.PoolMonitor()))
+//	conn.Use(m.Interceptor())
+//	http.Handle("/metrics", m.Handler())
+package metrics
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"expvar"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/neghi-go/database"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Error classes used to label failed operations.
+const (
+	ClassTimeout      = "timeout"
+	ClassCanceled     = "canceled"
+	ClassNotFound     = "not_found"
+	ClassDuplicateKey = "duplicate_key"
+	ClassValidation   = "validation"
+	ClassNetwork      = "network"
+	ClassOther        = "other"
+)
+
+// codeDocumentValidationFailure is returned when a write fails the
+// collection's $jsonSchema validator.
+const codeDocumentValidationFailure = 121
+
+var (
+	// DefaultLatencyBuckets are the upper bounds, in seconds, of the
+	// operation duration histogram.
+	DefaultLatencyBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	// DefaultDocumentBuckets are the upper bounds of the returned documents
+	// histogram.
+	DefaultDocumentBuckets = []float64{0, 1, 5, 10, 50, 100, 500, 1000, 5000, 10000}
+)
+
+// readOps are the operations whose document count is a number of returned
+// documents.
+var readOps = map[string]bool{
+	database.OpFind:      true,
+	database.OpFindOne:   true,
+	database.OpDistinct:  true,
+	database.OpAggregate: true,
+}
+
+type opKey struct {
+	System     string
+	Collection string
+	Operation  string
+}
+
+type opStats struct {
+	latency *histogram
+	docs    *histogram
+	errors  map[string]uint64
+}
+
+type poolStats struct {
+	open     int64
+	inUse    int64
+	waiting  int64
+	cleared  uint64
+	failures map[string]uint64
+}
+
+// Metrics collects operation and pool metrics. It is safe for concurrent use.
+type Metrics struct {
+	latencyBuckets  []float64
+	documentBuckets []float64
+
+	mu    sync.Mutex
+	ops   map[opKey]*opStats
+	pools map[string]*poolStats
+}
+
+type Option func(*Metrics)
+
+// WithLatencyBuckets replaces DefaultLatencyBuckets.
+func WithLatencyBuckets(bounds ...float64) Option {
+	return func(m *Metrics) {
+		m.latencyBuckets = slices.Sorted(slices.Values(bounds))
+	}
+}
+
+// WithDocumentBuckets replaces DefaultDocumentBuckets.
+func WithDocumentBuckets(bounds ...float64) Option {
+	return func(m *Metrics) {
+		m.documentBuckets = slices.Sorted(slices.Values(bounds))
+	}
+}
+
+func New(opts ...Option) *Metrics {
+	m := &Metrics{
+		latencyBuckets:  DefaultLatencyBuckets,
+		documentBuckets: DefaultDocumentBuckets,
+		ops:             map[opKey]*opStats{},
+		pools:           map[string]*poolStats{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Interceptor returns an interceptor recording every operation it sees.
+func (m *Metrics) Interceptor() database.Interceptor {
+	return func(ctx context.Context, op *database.OpInfo, next func(context.Context) error) error {
+		err := next(ctx)
+		m.observe(op, err)
+		return err
+	}
+}
+
+func (m *Metrics) observe(op *database.OpInfo, err error) {
+	key := opKey{System: op.System, Collection: op.Collection, Operation: op.Operation}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, ok := m.ops[key]
+	if !ok {
+		stats = &opStats{
+			latency: newHistogram(m.latencyBuckets),
+			docs:    newHistogram(m.documentBuckets),
+			errors:  map[string]uint64{},
+		}
+		m.ops[key] = stats
+	}
+	stats.latency.observe(op.Duration.Seconds())
+	if err != nil {
+		stats.errors[Classify(err)]++
+		return
+	}
+	if readOps[op.Operation] {
+		stats.docs.observe(float64(op.Docs))
+	}
+}
+
+// PoolMonitor returns a driver pool monitor maintaining the connection gauges,
+// to be passed to mongodb.WithPoolMonitor.
+func (m *Metrics) PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{Event: m.poolEvent}
+}
+
+func (m *Metrics) poolEvent(e *event.PoolEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pool, ok := m.pools[e.Address]
+	if !ok {
+		pool = &poolStats{failures: map[string]uint64{}}
+		m.pools[e.Address] = pool
+	}
+	switch e.Type {
+	case event.ConnectionCreated:
+		pool.open++
+	case event.ConnectionClosed:
+		pool.open--
+	case event.ConnectionCheckOutStarted:
+		pool.waiting++
+	case event.ConnectionCheckedOut:
+		pool.waiting--
+		pool.inUse++
+	case event.ConnectionCheckOutFailed:
+		pool.waiting--
+		pool.failures[e.Reason]++
+	case event.ConnectionCheckedIn:
+		pool.inUse--
+	case event.ConnectionPoolCleared:
+		pool.cleared++
+	}
+}
+
+// Classify maps an operation error onto one of the error classes.
+func Classify(err error) string {
+	var serverErr mongo.ServerError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ClassCanceled
+	case mongo.IsTimeout(err):
+		return ClassTimeout
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return ClassNotFound
+	case mongo.IsDuplicateKeyError(err):
+		return ClassDuplicateKey
+	case errors.As(err, &serverErr) && serverErr.HasErrorCode(codeDocumentValidationFailure):
+		return ClassValidation
+	case mongo.IsNetworkError(err):
+		return ClassNetwork
+	default:
+		return ClassOther
+	}
+}
+
+// OperationSnapshot is the state of one collection and operation pair.
+type OperationSnapshot struct {
+	System     string            `json:"system"`
+	Collection string            `json:"collection"`
+	Operation  string            `json:"operation"`
+	Latency    HistogramSnapshot `json:"latency_seconds"`
+	Documents  HistogramSnapshot `json:"returned_documents"`
+	Errors     map[string]uint64 `json:"errors"`
+}
+
+// PoolSnapshot is the state of the connection pool of one server.
+type PoolSnapshot struct {
+	Address          string            `json:"address"`
+	Open             int64             `json:"open"`
+	InUse            int64             `json:"in_use"`
+	Waiting          int64             `json:"waiting"`
+	Cleared          uint64            `json:"cleared"`
+	CheckoutFailures map[string]uint64 `json:"checkout_failures"`
+}
+
+// Snapshot is a consistent copy of all metrics.
+type Snapshot struct {
+	Operations []OperationSnapshot `json:"operations"`
+	Pools      []PoolSnapshot      `json:"pools"`
+}
+
+// Snapshot copies the current metrics, sorted by collection and operation
+// and by pool address.
+func (m *Metrics) Snapshot() Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := Snapshot{Operations: []OperationSnapshot{}, Pools: []PoolSnapshot{}}
+	keys := slices.SortedFunc(maps.Keys(m.ops), func(a, b opKey) int {
+		return cmp.Or(
+			cmp.Compare(a.System, b.System),
+			cmp.Compare(a.Collection, b.Collection),
+			cmp.Compare(a.Operation, b.Operation),
+		)
+	})
+	for _, key := range keys {
+		stats := m.ops[key]
+		res.Operations = append(res.Operations, OperationSnapshot{
+			System:     key.System,
+			Collection: key.Collection,
+			Operation:  key.Operation,
+			Latency:    stats.latency.snapshot(),
+			Documents:  stats.docs.snapshot(),
+			Errors:     maps.Clone(stats.errors),
+		})
+	}
+	for _, addr := range slices.Sorted(maps.Keys(m.pools)) {
+		pool := m.pools[addr]
+		res.Pools = append(res.Pools, PoolSnapshot{
+			Address:          addr,
+			Open:             pool.open,
+			InUse:            pool.inUse,
+			Waiting:          pool.waiting,
+			Cleared:          pool.cleared,
+			CheckoutFailures: maps.Clone(pool.failures),
+		})
+	}
+	return res
+}
+
+// Publish exposes the metrics under name on the expvar endpoint. Like
+// expvar.Publish it panics if the name is already in use.
+func (m *Metrics) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return m.Snapshot()
+	}))
+}
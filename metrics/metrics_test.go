@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/neghi-go/database"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "Test Canceled", err: fmt.Errorf("find: %w", context.Canceled), want: ClassCanceled},
+		{name: "Test Deadline", err: context.DeadlineExceeded, want: ClassTimeout},
+		{name: "Test Not Found", err: mongo.ErrNoDocuments, want: ClassNotFound},
+		{
+			name: "Test Duplicate Key",
+			err:  mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}},
+			want: ClassDuplicateKey,
+		},
+		{
+			name: "Test Validation",
+			err:  mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 121}}},
+			want: ClassValidation,
+		},
+		{name: "Test Other", err: errors.New("boom"), want: ClassOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, Classify(tt.err))
+		})
+	}
+}
+
+func TestInterceptor(t *testing.T) {
+	m := New()
+	chain := []database.Interceptor{m.Interceptor()}
+	errDup := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}
+
+	op := &database.OpInfo{System: "mongodb", Collection: "users", Operation: database.OpInsert}
+	err := database.Intercept(context.Background(), chain, op, func(context.Context) error { return errDup })
+	require.Equal(t, errDup, err)
+
+	snap := m.Snapshot()
+	require.Len(t, snap.Operations, 1)
+	require.Equal(t, uint64(1), snap.Operations[0].Latency.Count)
+	require.Equal(t, map[string]uint64{ClassDuplicateKey: 1}, snap.Operations[0].Errors)
+}
+
+func TestObserve(t *testing.T) {
+	m := New(WithLatencyBuckets(0.1, 0.01), WithDocumentBuckets(1, 10))
+	record := func(operation string, docs int64, d time.Duration, err error) {
+		m.observe(&database.OpInfo{System: "mongodb", Collection: "users", Operation: operation, Docs: docs, Duration: d}, err)
+	}
+	record(database.OpFind, 3, 5*time.Millisecond, nil)
+	record(database.OpFind, 20, 50*time.Millisecond, nil)
+	record(database.OpFind, 0, time.Second, context.DeadlineExceeded)
+	record(database.OpUpdate, 1, time.Millisecond, nil)
+
+	snap := m.Snapshot()
+	require.Len(t, snap.Operations, 2)
+
+	find := snap.Operations[0]
+	require.Equal(t, database.OpFind, find.Operation)
+	require.Equal(t, []Bucket{{UpperBound: 0.01, Count: 1}, {UpperBound: 0.1, Count: 2}}, find.Latency.Buckets)
+	require.Equal(t, uint64(3), find.Latency.Count)
+	require.Equal(t, []Bucket{{UpperBound: 1, Count: 0}, {UpperBound: 10, Count: 1}}, find.Documents.Buckets)
+	require.Equal(t, uint64(2), find.Documents.Count)
+	require.Equal(t, map[string]uint64{ClassTimeout: 1}, find.Errors)
+
+	// writes report matched documents, which are not returned ones
+	update := snap.Operations[1]
+	require.Equal(t, database.OpUpdate, update.Operation)
+	require.Zero(t, update.Documents.Count)
+}
+
+func TestPoolMonitor(t *testing.T) {
+	m := New()
+	monitor := m.PoolMonitor()
+	for _, typ := range []string{
+		event.ConnectionCreated, event.ConnectionCreated,
+		event.ConnectionCheckOutStarted, event.ConnectionCheckedOut,
+		event.ConnectionCheckOutStarted, event.ConnectionCheckedOut,
+		event.ConnectionCheckedIn,
+		event.ConnectionCheckOutStarted,
+		event.ConnectionClosed,
+		event.ConnectionPoolCleared,
+	} {
+		monitor.Event(&event.PoolEvent{Type: typ, Address: "db:27017"})
+	}
+	monitor.Event(&event.PoolEvent{Type: event.ConnectionCheckOutStarted, Address: "db:27017"})
+	monitor.Event(&event.PoolEvent{Type: event.ConnectionCheckOutFailed, Address: "db:27017", Reason: event.ReasonTimedOut})
+
+	require.Equal(t, []PoolSnapshot{{
+		Address:          "db:27017",
+		Open:             1,
+		InUse:            1,
+		Waiting:          1,
+		Cleared:          1,
+		CheckoutFailures: map[string]uint64{event.ReasonTimedOut: 1},
+	}}, m.Snapshot().Pools)
+}
+
+func TestWritePrometheus(t *testing.T) {
+	m := New(WithLatencyBuckets(0.01), WithDocumentBuckets(10))
+	m.observe(&database.OpInfo{System: "mongodb", Collection: `we"ird`, Operation: database.OpFind, Docs: 2, Duration: 5 * time.Millisecond}, nil)
+	m.observe(&database.OpInfo{System: "mongodb", Collection: `we"ird`, Operation: database.OpFind, Duration: time.Second}, errors.New("boom"))
+	m.PoolMonitor().Event(&event.PoolEvent{Type: event.ConnectionCreated, Address: "db:27017"})
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	require.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+
+	var want bytes.Buffer
+	labels := `system="mongodb",collection="we\"ird",operation="find"`
+	fmt.Fprintf(&want, `# HELP db_operation_duration_seconds Duration of database operations.
+# TYPE db_operation_duration_seconds histogram
+db_operation_duration_seconds_bucket{%[1]s,le="0.01"} 1
+db_operation_duration_seconds_bucket{%[1]s,le="+Inf"} 2
+db_operation_duration_seconds_sum{%[1]s} 1.005
+db_operation_duration_seconds_count{%[1]s} 2
+# HELP db_operation_returned_documents Documents returned by successful reads.
+# TYPE db_operation_returned_documents histogram
+db_operation_returned_documents_bucket{%[1]s,le="10"} 1
+db_operation_returned_documents_bucket{%[1]s,le="+Inf"} 1
+db_operation_returned_documents_sum{%[1]s} 2
+db_operation_returned_documents_count{%[1]s} 1
+# HELP db_operation_errors_total Failed database operations by error class.
+# TYPE db_operation_errors_total counter
+db_operation_errors_total{%[1]s,class="other"} 1
+# HELP db_pool_connections_open Open connections in the pool.
+# TYPE db_pool_connections_open gauge
+db_pool_connections_open{address="db:27017"} 1
+# HELP db_pool_connections_in_use Connections checked out of the pool.
+# TYPE db_pool_connections_in_use gauge
+db_pool_connections_in_use{address="db:27017"} 0
+# HELP db_pool_waiting Operations waiting for a connection.
+# TYPE db_pool_waiting gauge
+db_pool_waiting{address="db:27017"} 0
+# HELP db_pool_checkout_failures_total Failed connection check outs by reason.
+# TYPE db_pool_checkout_failures_total counter
+# HELP db_pool_cleared_total Times the pool was cleared after an error.
+# TYPE db_pool_cleared_total counter
+db_pool_cleared_total{address="db:27017"} 0
+`, labels)
+	require.Equal(t, want.String(), rec.Body.String())
+}
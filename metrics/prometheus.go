@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Handler serves the metrics in the Prometheus text exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WritePrometheus(w)
+	})
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	snap := m.Snapshot()
+	bw := bufio.NewWriter(w)
+
+	header(bw, "db_operation_duration_seconds", "histogram", "Duration of database operations.")
+	for _, op := range snap.Operations {
+		writeHistogram(bw, "db_operation_duration_seconds", opLabels(op), op.Latency)
+	}
+	header(bw, "db_operation_returned_documents", "histogram", "Documents returned by successful reads.")
+	for _, op := range snap.Operations {
+		if op.Documents.Count > 0 {
+			writeHistogram(bw, "db_operation_returned_documents", opLabels(op), op.Documents)
+		}
+	}
+	header(bw, "db_operation_errors_total", "counter", "Failed database operations by error class.")
+	for _, op := range snap.Operations {
+		for _, class := range slices.Sorted(maps.Keys(op.Errors)) {
+			sample(bw, "db_operation_errors_total", append(opLabels(op), "class", class), float64(op.Errors[class]))
+		}
+	}
+
+	header(bw, "db_pool_connections_open", "gauge", "Open connections in the pool.")
+	for _, pool := range snap.Pools {
+		sample(bw, "db_pool_connections_open", []string{"address", pool.Address}, float64(pool.Open))
+	}
+	header(bw, "db_pool_connections_in_use", "gauge", "Connections checked out of the pool.")
+	for _, pool := range snap.Pools {
+		sample(bw, "db_pool_connections_in_use", []string{"address", pool.Address}, float64(pool.InUse))
+	}
+	header(bw, "db_pool_waiting", "gauge", "Operations waiting for a connection.")
+	for _, pool := range snap.Pools {
+		sample(bw, "db_pool_waiting", []string{"address", pool.Address}, float64(pool.Waiting))
+	}
+	header(bw, "db_pool_checkout_failures_total", "counter", "Failed connection check outs by reason.")
+	for _, pool := range snap.Pools {
+		for _, reason := range slices.Sorted(maps.Keys(pool.CheckoutFailures)) {
+			sample(bw, "db_pool_checkout_failures_total", []string{"address", pool.Address, "reason", reason},
+				float64(pool.CheckoutFailures[reason]))
+		}
+	}
+	header(bw, "db_pool_cleared_total", "counter", "Times the pool was cleared after an error.")
+	for _, pool := range snap.Pools {
+		sample(bw, "db_pool_cleared_total", []string{"address", pool.Address}, float64(pool.Cleared))
+	}
+	return bw.Flush()
+}
+
+func opLabels(op OperationSnapshot) []string {
+	return []string{"system", op.System, "collection", op.Collection, "operation", op.Operation}
+}
+
+func header(w *bufio.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func writeHistogram(w *bufio.Writer, name string, labels []string, h HistogramSnapshot) {
+	for _, b := range h.Buckets {
+		sample(w, name+"_bucket", append(slices.Clone(labels), "le", formatFloat(b.UpperBound)), float64(b.Count))
+	}
+	sample(w, name+"_bucket", append(slices.Clone(labels), "le", "+Inf"), float64(h.Count))
+	sample(w, name+"_sum", labels, h.Sum)
+	sample(w, name+"_count", labels, float64(h.Count))
+}
+
+// sample writes one line, labels being alternating names and values.
+func sample(w *bufio.Writer, name string, labels []string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i := 0; i < len(labels); i += 2 {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, `%s="%s"`, labels[i], escapeLabel(labels[i+1]))
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,317 @@
+// Package migrate runs ordered, versioned data migrations against a mongo
+// database and records which versions have been applied.
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	historyCollection = "_migrations"
+	lockCollection    = "_migrations_lock"
+	lockID            = "lock"
+)
+
+var (
+	ErrLocked      = errors.New("error: migrations are locked by another process")
+	ErrNoDown      = errors.New("error: migration has no down function")
+	ErrDuplicate   = errors.New("error: migration version registered more than once")
+	ErrInvalidStep = errors.New("error: migration version must be greater than zero")
+)
+
+// Migration is a single versioned change. Versions are applied in ascending
+// order, typically a timestamp such as 20250105120000.
+type Migration struct {
+	Version     int64
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+	Down        func(ctx context.Context, db *mongo.Database) error
+}
+
+// Status reports whether a migration has been applied. Migrations found in the
+// history but not registered are reported with Registered set to false.
+type Status struct {
+	Version     int64
+	Description string
+	Applied     bool
+	AppliedAt   time.Time
+	Registered  bool
+}
+
+type record struct {
+	Version     int64     `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+var registry = struct {
+	sync.Mutex
+	migrations []Migration
+}{}
+
+// Register adds a migration to the package registry, usually from an init
+// function, so tools can run every migration linked into the binary.
+func Register(m Migration) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.migrations = append(registry.migrations, m)
+}
+
+// Registered returns the migrations added with Register.
+func Registered() []Migration {
+	registry.Lock()
+	defer registry.Unlock()
+	return slices.Clone(registry.migrations)
+}
+
+type Migrator struct {
+	db         *mongo.Database
+	migrations []Migration
+	owner      string
+	lockTTL    time.Duration
+	lockWait   time.Duration
+	dryRun     bool
+}
+
+type Option func(*Migrator)
+
+// WithDryRun reports the migrations Up and Down would run without running or
+// recording them.
+func WithDryRun() Option {
+	return func(m *Migrator) {
+		m.dryRun = true
+	}
+}
+
+// WithLockTTL sets how long the lock is held before another process may take
+// it over, in case the holder died. The lock is renewed before each migration.
+func WithLockTTL(ttl time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockTTL = ttl
+	}
+}
+
+// WithLockWait sets how long to wait for another process to release the lock
+// before giving up with ErrLocked.
+func WithLockWait(wait time.Duration) Option {
+	return func(m *Migrator) {
+		m.lockWait = wait
+	}
+}
+
+func New(db *mongo.Database, migrations []Migration, opts ...Option) (*Migrator, error) {
+	sorted := slices.Clone(migrations)
+	slices.SortFunc(sorted, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	for i, mg := range sorted {
+		if mg.Version <= 0 {
+			return nil, ErrInvalidStep
+		}
+		if i > 0 && sorted[i-1].Version == mg.Version {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicate, mg.Version)
+		}
+	}
+
+	host, _ := os.Hostname()
+	m := &Migrator{
+		db:         db,
+		migrations: sorted,
+		owner:      host + "/" + uuid.NewString(),
+		lockTTL:    10 * time.Minute,
+		lockWait:   time.Minute,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
+}
+
+// Status lists every registered or applied migration in version order.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var res []Status
+	for _, mg := range m.migrations {
+		st := Status{Version: mg.Version, Description: mg.Description, Registered: true}
+		if r, ok := applied[mg.Version]; ok {
+			st.Applied = true
+			st.AppliedAt = r.AppliedAt
+			delete(applied, mg.Version)
+		}
+		res = append(res, st)
+	}
+	for _, r := range applied {
+		res = append(res, Status{Version: r.Version, Description: r.Description, Applied: true, AppliedAt: r.AppliedAt})
+	}
+	slices.SortFunc(res, func(a, b Status) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return res, nil
+}
+
+// Up applies the pending migrations up to and including version to, or all of
+// them when to is zero, and returns the migrations it ran.
+func (m *Migrator) Up(ctx context.Context, to int64) ([]Migration, error) {
+	return m.run(ctx, func(applied map[int64]record) []Migration {
+		var plan []Migration
+		for _, mg := range m.migrations {
+			if _, ok := applied[mg.Version]; !ok && (to <= 0 || mg.Version <= to) {
+				plan = append(plan, mg)
+			}
+		}
+		return plan
+	}, m.up)
+}
+
+// Down reverts the applied migrations with a version greater than to, newest
+// first, and returns the migrations it reverted.
+func (m *Migrator) Down(ctx context.Context, to int64) ([]Migration, error) {
+	return m.run(ctx, func(applied map[int64]record) []Migration {
+		var plan []Migration
+		for _, mg := range slices.Backward(m.migrations) {
+			if _, ok := applied[mg.Version]; ok && mg.Version > to {
+				plan = append(plan, mg)
+			}
+		}
+		return plan
+	}, m.down)
+}
+
+func (m *Migrator) run(ctx context.Context, planner func(map[int64]record) []Migration, step func(context.Context, Migration) error) ([]Migration, error) {
+	if !m.dryRun {
+		if err := m.lock(ctx); err != nil {
+			return nil, err
+		}
+		defer m.unlock(context.WithoutCancel(ctx))
+	}
+
+	// the history is read under the lock so a process that waited sees the
+	// migrations the previous holder applied
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan := planner(applied)
+	if m.dryRun {
+		return plan, nil
+	}
+
+	var done []Migration
+	for _, mg := range plan {
+		if err := m.acquire(ctx); err != nil {
+			return done, err
+		}
+		if err := step(ctx, mg); err != nil {
+			return done, fmt.Errorf("migration %d: %w", mg.Version, err)
+		}
+		done = append(done, mg)
+	}
+	return done, nil
+}
+
+func (m *Migrator) up(ctx context.Context, mg Migration) error {
+	if mg.Up != nil {
+		if err := mg.Up(ctx, m.db); err != nil {
+			return err
+		}
+	}
+	_, err := m.db.Collection(historyCollection).InsertOne(ctx, record{
+		Version:     mg.Version,
+		Description: mg.Description,
+		AppliedAt:   time.Now().UTC(),
+	})
+	return err
+}
+
+func (m *Migrator) down(ctx context.Context, mg Migration) error {
+	if mg.Down == nil {
+		return ErrNoDown
+	}
+	if err := mg.Down(ctx, m.db); err != nil {
+		return err
+	}
+	_, err := m.db.Collection(historyCollection).DeleteOne(ctx, bson.D{{Key: "_id", Value: mg.Version}})
+	return err
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]record, error) {
+	result, err := m.db.Collection(historyCollection).Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	var records []record
+	if err := result.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	res := map[int64]record{}
+	for _, r := range records {
+		res[r.Version] = r
+	}
+	return res, nil
+}
+
+// lock waits for the migration lock, polling until it is free or lockWait has
+// passed.
+func (m *Migrator) lock(ctx context.Context) error {
+	deadline := time.Now().Add(m.lockWait)
+	for {
+		err := m.acquire(ctx)
+		if !errors.Is(err, ErrLocked) {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// acquire takes or renews the lock. The upsert only matches a lock that has
+// expired or is already ours; otherwise inserting the fixed id fails with a
+// duplicate key error, meaning someone else holds it.
+func (m *Migrator) acquire(ctx context.Context) error {
+	now := time.Now().UTC()
+	filter := bson.D{
+		{Key: "_id", Value: lockID},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "expires_at", Value: bson.D{{Key: "$lt", Value: now}}}},
+			bson.D{{Key: "owner", Value: m.owner}},
+		}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "owner", Value: m.owner},
+		{Key: "expires_at", Value: now.Add(m.lockTTL)},
+	}}}
+	_, err := m.db.Collection(lockCollection).UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrLocked
+	}
+	return err
+}
+
+func (m *Migrator) unlock(ctx context.Context) error {
+	_, err := m.db.Collection(lockCollection).DeleteOne(ctx, bson.D{
+		{Key: "_id", Value: lockID},
+		{Key: "owner", Value: m.owner},
+	})
+	return err
+}
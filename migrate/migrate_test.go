@@ -0,0 +1,157 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/neghi-go/database/mongodb"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var test_url string
+
+func TestMain(m *testing.M) {
+	client := testcontainers.ContainerRequest{
+		Image:        "mongo:8.0",
+		ExposedPorts: []string{"27017/tcp"},
+	}
+	mongoClient, err := testcontainers.GenericContainer(context.Background(), testcontainers.GenericContainerRequest{
+		ContainerRequest: client,
+		Started:          true,
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	test_url, _ = mongoClient.Endpoint(context.Background(), "")
+	exitVal := m.Run()
+	testcontainers.TerminateContainer(mongoClient)
+	os.Exit(exitVal)
+}
+
+func rename(from, to string) Migration {
+	return Migration{
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("users").UpdateMany(ctx, bson.D{},
+				bson.D{{Key: "$rename", Value: bson.D{{Key: from, Value: to}}}})
+			return err
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("users").UpdateMany(ctx, bson.D{},
+				bson.D{{Key: "$rename", Value: bson.D{{Key: to, Value: from}}}})
+			return err
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name       string
+		migrations []Migration
+		wantErr    error
+	}{
+		{
+			name:       "Test Valid Migrations",
+			migrations: []Migration{{Version: 2}, {Version: 1}},
+		},
+		{
+			name:       "Test Duplicate Version",
+			migrations: []Migration{{Version: 1}, {Version: 1}},
+			wantErr:    ErrDuplicate,
+		},
+		{
+			name:       "Test Zero Version",
+			migrations: []Migration{{Version: 0}},
+			wantErr:    ErrInvalidStep,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(nil, tt.migrations)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	conn, err := mongodb.New("mongodb://"+test_url, "migrate")
+	require.NoError(t, err)
+	db := conn.Database()
+
+	_, err = db.Collection("users").InsertOne(context.Background(), bson.D{{Key: "name", Value: "Jon"}})
+	require.NoError(t, err)
+
+	first := rename("name", "full_name")
+	first.Version, first.Description = 1, "rename name"
+	second := rename("full_name", "display_name")
+	second.Version, second.Description = 2, "rename full name"
+	migrations := []Migration{second, first}
+
+	t.Run("Dry Run", func(t *testing.T) {
+		m, err := New(db, migrations, WithDryRun())
+		require.NoError(t, err)
+		plan, err := m.Up(context.Background(), 0)
+		require.NoError(t, err)
+		require.Len(t, plan, 2)
+
+		status, err := m.Status(context.Background())
+		require.NoError(t, err)
+		require.False(t, status[0].Applied)
+	})
+
+	t.Run("Up To Version", func(t *testing.T) {
+		m, err := New(db, migrations)
+		require.NoError(t, err)
+		done, err := m.Up(context.Background(), 1)
+		require.NoError(t, err)
+		require.Len(t, done, 1)
+
+		status, err := m.Status(context.Background())
+		require.NoError(t, err)
+		require.True(t, status[0].Applied)
+		require.False(t, status[1].Applied)
+	})
+
+	t.Run("Up To Latest", func(t *testing.T) {
+		m, err := New(db, migrations)
+		require.NoError(t, err)
+		done, err := m.Up(context.Background(), 0)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), done[0].Version)
+
+		count, err := db.Collection("users").CountDocuments(context.Background(), bson.D{{Key: "display_name", Value: "Jon"}})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), count)
+	})
+
+	t.Run("Down", func(t *testing.T) {
+		m, err := New(db, migrations)
+		require.NoError(t, err)
+		done, err := m.Down(context.Background(), 0)
+		require.NoError(t, err)
+		require.Len(t, done, 2)
+
+		count, err := db.Collection("users").CountDocuments(context.Background(), bson.D{{Key: "name", Value: "Jon"}})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), count)
+	})
+
+	t.Run("Locked", func(t *testing.T) {
+		holder, err := New(db, migrations)
+		require.NoError(t, err)
+		require.NoError(t, holder.acquire(context.Background()))
+		defer holder.unlock(context.Background())
+
+		m, err := New(db, migrations, WithLockWait(time.Second))
+		require.NoError(t, err)
+		_, err = m.Up(context.Background(), 0)
+		require.ErrorIs(t, err, ErrLocked)
+	})
+}
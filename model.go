@@ -2,6 +2,7 @@ package database
 
 import (
 	"context"
+	"iter"
 )
 
 type Model[T any] interface {
@@ -9,6 +10,17 @@ type Model[T any] interface {
 	Query(query_params ...Params) Query[T]
 	Save(doc ...T) error
 	ExecRaw() error
+	// Scope registers params under name, applied by the queries given
+	// UseScope(name)
+	Scope(name string, params ...Params) Model[T]
+	// DefaultScope registers params applied to every query not given
+	// Unscoped
+	DefaultScope(params ...Params) Model[T]
+	// Watch streams the changes made to the documents of the model until
+	// ctx is done. Filters given with WithFilter apply to the document after
+	// the write, and to the document before it for deletes when the backend
+	// keeps it
+	Watch(ctx context.Context, params ...Params) (<-chan ChangeEvent[T], error)
 }
 
 // Query interface defines the structure of the store queries
@@ -19,12 +31,59 @@ type Query[T any] interface {
 	First() (*T, error)
 	// All returns all the document that matches a query
 	All() ([]*T, error)
+	// Iter streams the documents that match a query, keeping the underlying
+	// cursor open until iteration completes or the loop breaks
+	Iter() iter.Seq2[*T, error]
+	// Each calls fn for every document that matches a query, stopping at the
+	// first error returned by the query or by fn
+	Each(fn func(*T) error) error
+	// Page returns up to size documents following the position encoded in
+	// token, ordered by the query sort keys with the id as tiebreaker. An
+	// empty token starts from the first document
+	Page(size int64, token string) (Page[T], error)
+	// Paginate returns the 1-based page of perPage documents that match a
+	// query along with the total number of matches
+	Paginate(page, perPage int64) (Paginated[T], error)
+	// Distinct returns the unique values of field across the documents that
+	// match a query
+	Distinct(field string) ([]any, error)
 	// Update updates the document that matches a query
 	Update(doc T) error
 	// UpdateMany updates all the document that matches a query
 	UpdateMany(doc T) error
+	// UpdateFields sets only the given fields of doc, named by their db keys,
+	// on the document that matches a query
+	UpdateFields(doc T, fields ...string) error
+	// Set adds a field assignment to the update run by Apply or ApplyMany.
+	// Fields are named by their db keys, optionally followed by a nested
+	// path, and must exist on the model
+	Set(field string, value any) Query[T]
+	// Unset adds the removal of a field to the update
+	Unset(field string) Query[T]
+	// Inc adds the increment of a numeric field by the given amount
+	Inc(field string, by any) Query[T]
+	// Push adds appending values to an array field
+	Push(field string, values ...any) Query[T]
+	// Pull adds removing every occurrence of value from an array field
+	Pull(field string, value any) Query[T]
+	// AddToSet adds appending values missing from an array field
+	AddToSet(field string, values ...any) Query[T]
+	// Min adds lowering a field to value when value is smaller
+	Min(field string, value any) Query[T]
+	// Max adds raising a field to value when value is greater
+	Max(field string, value any) Query[T]
+	// CurrentDate adds setting a field to the current time
+	CurrentDate(field string) Query[T]
+	// Apply runs the update built with Set and the other operators on the
+	// document that matches a query
+	Apply() error
+	// ApplyMany runs the update on all the documents that match a query
+	ApplyMany() error
 	// Delete deletes the document that matches a query
 	Delete() error
 	// DeleteMany deletes all document that matches the query
 	DeleteMany() error
+	// Explain reports the plan the database picks for the query, running
+	// it as well unless verbosity is ExplainQueryPlanner
+	Explain(verbosity ExplainVerbosity) (Plan, error)
 }
@@ -2,38 +2,119 @@ package mongodb
 
 import (
 	"context"
+	"slices"
+	"sync"
 	"time"
 
+	"github.com/neghi-go/database"
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
-func initClient(url string) (*mongo.Client, error) {
+// Option configures New.
+type Option func(*clientConfig)
+
+type clientConfig struct {
+	client *options.ClientOptions
+	slow   *SlowQueryLog
+}
+
+// WithPoolMonitor reports connection pool events to monitor, for example the
+// one returned by metrics.PoolMonitor.
+func WithPoolMonitor(monitor *event.PoolMonitor) Option {
+	return func(c *clientConfig) {
+		c.client.SetPoolMonitor(monitor)
+	}
+}
+
+func initClient(url string, cfg clientConfig) (*mongo.Client, error) {
 	registry := mongoRegistry
 	registry.RegisterTypeEncoder(tUUID, bson.ValueEncoderFunc(uuidEncodeValue))
 	registry.RegisterTypeDecoder(tUUID, bson.ValueDecoderFunc(uuidDecodeValue))
 
-	return mongo.Connect(options.Client().ApplyURI(url).SetRegistry(registry))
+	return mongo.Connect(cfg.client.ApplyURI(url).SetRegistry(registry))
 }
 
+var _ database.Transactor = (*mongoDatabase)(nil)
+
 type mongoDatabase struct {
 	db *mongo.Database
+
+	mu           sync.RWMutex
+	interceptors []database.Interceptor
 }
 
-func New(url, db string) (*mongoDatabase, error) {
+func New(url, db string, opts ...Option) (*mongoDatabase, error) {
+	client, cfg, err := connect(url, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newDatabase(client.Database(db), cfg), nil
+}
+
+// connect opens a client and checks the server is reachable.
+func connect(url string, opts []Option) (*mongo.Client, clientConfig, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
-	client, err := initClient(url)
+	cfg := clientConfig{client: options.Client()}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	client, err := initClient(url, cfg)
 	if err != nil {
-		return nil, err
+		return nil, cfg, err
 	}
 	if err := client.Ping(ctx, nil); err != nil {
-		return nil, err
+		return nil, cfg, err
 	}
-	return &mongoDatabase{
-		db: client.Database(db),
-	}, nil
+	return client, cfg, nil
+}
+
+func newDatabase(db *mongo.Database, cfg clientConfig) *mongoDatabase {
+	conn := &mongoDatabase{
+		db: db,
+	}
+	if cfg.slow != nil {
+		conn.Use(conn.slowQueries(*cfg.slow))
+	}
+	return conn
+}
+
+// Database returns the underlying driver handle, for tools such as migrations
+// that work below the model layer.
+func (m *mongoDatabase) Database() *mongo.Database {
+	return m.db
+}
+
+// Use adds interceptors that run around every operation of every model
+// registered on this database, including models registered before the call.
+func (m *mongoDatabase) Use(interceptors ...database.Interceptor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.interceptors = append(m.interceptors, interceptors...)
+}
+
+func (m *mongoDatabase) chain() []database.Interceptor {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return slices.Clone(m.interceptors)
+}
+
+// WithTransaction implements database.Transactor. Transactions need a replica
+// set or a sharded cluster.
+func (m *mongoDatabase) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	sess, err := m.db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer sess.EndSession(context.WithoutCancel(ctx))
+
+	_, err = sess.WithTransaction(ctx, func(ctx context.Context) (interface{}, error) {
+		return nil, fn(ctx)
+	})
+	return err
 }
 
 func (m *mongoDatabase) Disconnect(ctx context.Context) error {
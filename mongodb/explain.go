@@ -0,0 +1,161 @@
+package mongodb
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/neghi-go/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// indexStages are the plan stages reading through an index.
+var indexStages = map[string]bool{
+	"IXSCAN":         true,
+	"COUNT_SCAN":     true,
+	"DISTINCT_SCAN":  true,
+	"EXPRESS_IXSCAN": true,
+	"IDHACK":         true,
+}
+
+// explainable reports whether op is a read that can be explained as a find on
+// its filter. Aggregations qualify as their pipeline opens with a $match on
+// the filter. Writes are left out, as running them again would have to go
+// through explain on the write itself, and watches never complete.
+func explainable(op *database.OpInfo) bool {
+	switch op.Operation {
+	case database.OpFind, database.OpFindOne, database.OpCount, database.OpDistinct, database.OpAggregate:
+		return true
+	default:
+		return false
+	}
+}
+
+// findCommand rebuilds the find command matching a read operation.
+func findCommand(op *database.OpInfo) bson.D {
+	filter := op.Filter
+	if filter == nil {
+		filter = bson.D{}
+	}
+	cmd := bson.D{{Key: "find", Value: op.Collection}, {Key: "filter", Value: filter}}
+	if sort, ok := op.Sort.(bson.D); ok && len(sort) > 0 {
+		cmd = append(cmd, bson.E{Key: "sort", Value: sort})
+	}
+	if op.Limit > 0 {
+		cmd = append(cmd, bson.E{Key: "limit", Value: op.Limit})
+	}
+	if op.Offset > 0 {
+		cmd = append(cmd, bson.E{Key: "skip", Value: op.Offset})
+	}
+	return cmd
+}
+
+func (m *mongoDatabase) explain(ctx context.Context, cmd bson.D, verbosity database.ExplainVerbosity) (database.Plan, error) {
+	raw, err := m.db.RunCommand(ctx, bson.D{
+		{Key: "explain", Value: cmd},
+		{Key: "verbosity", Value: string(verbosity)},
+	}).Raw()
+	if err != nil {
+		return database.Plan{}, err
+	}
+	return parseExplain(raw)
+}
+
+func parseExplain(raw bson.Raw) (database.Plan, error) {
+	var plan database.Plan
+	var err error
+	if plan.Raw, err = bson.MarshalExtJSON(raw, false, false); err != nil {
+		return plan, err
+	}
+
+	if winning, ok := raw.Lookup("queryPlanner", "winningPlan").DocumentOK(); ok {
+		// the slot based engine nests the classic shaped plan one level down
+		if inner, ok := winning.Lookup("queryPlan").DocumentOK(); ok {
+			winning = inner
+		}
+		plan.Summary = walkPlan(winning, &plan)
+	}
+
+	stats, ok := raw.Lookup("executionStats").DocumentOK()
+	if !ok {
+		return plan, nil
+	}
+	plan.DocsReturned, _ = stats.Lookup("nReturned").AsInt64OK()
+	plan.DocsExamined, _ = stats.Lookup("totalDocsExamined").AsInt64OK()
+	plan.KeysExamined, _ = stats.Lookup("totalKeysExamined").AsInt64OK()
+	millis, _ := stats.Lookup("executionTimeMillis").AsInt64OK()
+	plan.ExecutionTime = time.Duration(millis) * time.Millisecond
+	return plan, nil
+}
+
+// walkPlan records the stages and indexes of a plan stage and its inputs,
+// and renders them on one line, outermost first:
+// "LIMIT > FETCH > IXSCAN(email_1)" or "FETCH > OR[IXSCAN(a_1), IXSCAN(b_1)]".
+func walkPlan(stage bson.Raw, plan *database.Plan) string {
+	name, _ := stage.Lookup("stage").StringValueOK()
+	plan.Stages = append(plan.Stages, name)
+	summary := name
+
+	if name == "COLLSCAN" {
+		plan.CollectionScan = true
+	}
+	if indexStages[name] {
+		index, ok := stage.Lookup("indexName").StringValueOK()
+		if !ok && name == "IDHACK" {
+			index, ok = "_id_", true
+		}
+		if ok {
+			plan.Indexes = append(plan.Indexes, index)
+			summary += "(" + index + ")"
+		}
+	}
+
+	if input, ok := stage.Lookup("inputStage").DocumentOK(); ok {
+		return summary + " > " + walkPlan(input, plan)
+	}
+	if inputs, ok := stage.Lookup("inputStages").ArrayOK(); ok {
+		values, _ := inputs.Values()
+		var branches []string
+		for _, v := range values {
+			if input, ok := v.DocumentOK(); ok {
+				branches = append(branches, walkPlan(input, plan))
+			}
+		}
+		return summary + "[" + strings.Join(branches, ", ") + "]"
+	}
+	return summary
+}
+
+// Explain implements database.Query.
+func (m *MongoModel[T]) Explain(verbosity database.ExplainVerbosity) (database.Plan, error) {
+	if _, err := m.scope(); err != nil {
+		return database.Plan{}, err
+	}
+	ctx, op, collation := m.ctx, m.opInfo(database.OpExplain), m.collation
+	m.reset()
+
+	cmd := findCommand(op)
+	if collation != nil {
+		cmd = append(cmd, bson.E{Key: "collation", Value: collationDoc(collation)})
+	}
+	var plan database.Plan
+	err := m.intercept(ctx, op, func(ctx context.Context) (err error) {
+		plan, err = m.conn.explain(ctx, cmd, verbosity)
+		return err
+	})
+	return plan, err
+}
+
+// collationDoc renders the collation options set by convertCollation with the
+// field names the server expects.
+func collationDoc(c *options.Collation) bson.D {
+	doc := bson.D{{Key: "locale", Value: c.Locale}}
+	if c.Strength > 0 {
+		doc = append(doc, bson.E{Key: "strength", Value: c.Strength})
+	}
+	if c.NumericOrdering {
+		doc = append(doc, bson.E{Key: "numericOrdering", Value: true})
+	}
+	return doc
+}
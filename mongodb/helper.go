@@ -2,6 +2,11 @@ package mongodb
 
 import (
 	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/neghi-go/database"
@@ -10,24 +15,176 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
-func getIndexes[T any](model T) ([]mongo.IndexModel, error) {
-	var res []mongo.IndexModel
-	parsed, err := database.EncodeModel(model)
+func indexModel(spec database.IndexSpec) (mongo.IndexModel, error) {
+	if len(spec.Keys) == 0 {
+		return mongo.IndexModel{}, errors.New("index must have at least one key")
+	}
+	keys := bson.D{}
+	for _, k := range spec.Keys {
+		var val interface{}
+		switch k.Kind {
+		case database.IndexAsc, "":
+			val = 1
+		case database.IndexDesc:
+			val = -1
+		case database.IndexText:
+			val = "text"
+		case database.IndexHashed:
+			val = "hashed"
+		default:
+			return mongo.IndexModel{}, fmt.Errorf("unsupported index kind %q", k.Kind)
+		}
+		keys = append(keys, bson.E{Key: k.Field, Value: val})
+	}
+
+	opts := options.Index().SetUnique(spec.Unique)
+	if spec.Name != "" {
+		opts.SetName(spec.Name)
+	}
+	if spec.Sparse {
+		opts.SetSparse(true)
+	}
+	if spec.ExpireAfter != nil {
+		opts.SetExpireAfterSeconds(int32(*spec.ExpireAfter / time.Second))
+	}
+	if len(spec.Weights) > 0 {
+		weights := bson.D{}
+		for _, k := range slices.Sorted(maps.Keys(spec.Weights)) {
+			weights = append(weights, bson.E{Key: k, Value: spec.Weights[k]})
+		}
+		opts.SetWeights(weights)
+	}
+	if len(spec.Partial) > 0 {
+		opts.SetPartialFilterExpression(sortedDoc(spec.Partial))
+	}
+	if spec.Collation != nil {
+		opts.SetCollation(convertCollation(*spec.Collation))
+	}
+	return mongo.IndexModel{Keys: keys, Options: opts}, nil
+}
+
+// sortedDoc converts a map into a document with its keys in a stable order,
+// converting nested maps as well.
+func sortedDoc(m map[string]interface{}) bson.D {
+	res := bson.D{}
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		val := m[k]
+		if nested, ok := val.(map[string]interface{}); ok {
+			val = sortedDoc(nested)
+		}
+		res = append(res, bson.E{Key: k, Value: val})
+	}
+	return res
+}
+
+// filterValue converts a hex string compared against _id into the ObjectID
+// stored there, so models can be looked up by their mongoid field.
+func filterValue(key string, value interface{}) interface{} {
+	if key != "_id" {
+		return value
+	}
+	if s, ok := value.(string); ok {
+		if id, err := bson.ObjectIDFromHex(s); err == nil {
+			return id
+		}
+	}
+	return value
+}
+
+// querySummary renders the shape of a filter as extended JSON, keeping field
+// names and operators but replacing every value with "?".
+func querySummary(filter bson.D) string {
+	if len(filter) == 0 {
+		return "{}"
+	}
+	b, err := bson.MarshalExtJSON(filterShape(filter), false, false)
 	if err != nil {
-		return nil, err
+		return "{?}"
 	}
-	for _, e := range parsed {
-		if e.Index && e.MongoID {
-			return nil, errors.New("setting mongoid already sets index")
+	return string(b)
+}
+
+func filterShape(value interface{}) interface{} {
+	switch v := value.(type) {
+	case bson.D:
+		res := make(bson.D, 0, len(v))
+		for _, e := range v {
+			res = append(res, bson.E{Key: e.Key, Value: filterShape(e.Value)})
 		}
-		if e.Index {
-			res = append(res, mongo.IndexModel{
-				Keys:    bson.D{{Key: e.Key, Value: 1}},
-				Options: options.Index().SetUnique(e.Unique),
-			})
+		return res
+	case bson.M:
+		return filterShape(sortedDoc(v))
+	case map[string]interface{}:
+		return filterShape(sortedDoc(v))
+	case bson.A:
+		return filterShape([]interface{}(v))
+	case []interface{}:
+		res := make(bson.A, 0, len(v))
+		for _, e := range v {
+			res = append(res, filterShape(e))
 		}
+		return res
+	default:
+		return "?"
+	}
+}
+
+// sortValue maps an order type onto the direction used in a mongo sort.
+func sortValue(o database.OrderType) (int, error) {
+	switch o {
+	case database.ASC:
+		return 1, nil
+	case database.DESC:
+		return -1, nil
+	default:
+		return 0, fmt.Errorf("unsupported order type %d", o)
+	}
+}
+
+// sortStages builds the aggregation stages sorting by order. Keys with an
+// explicit null placement are preceded by a computed flag that is 1 for null
+// or missing values, sorted so nulls land first or last as requested.
+func sortStages(order bson.D, nulls map[string]database.NullsOrder) []bson.D {
+	if len(order) == 0 {
+		return nil
+	}
+	flags, sort, project := bson.D{}, bson.D{}, bson.D{}
+	for _, e := range order {
+		if n, ok := nulls[e.Key]; ok {
+			flag := "_nulls_" + strings.ReplaceAll(e.Key, ".", "_")
+			isNull := bson.D{{Key: "$eq", Value: bson.A{bson.D{{Key: "$ifNull", Value: bson.A{"$" + e.Key, nil}}}, nil}}}
+			flags = append(flags, bson.E{Key: flag, Value: bson.D{{Key: "$cond", Value: bson.A{isNull, 1, 0}}}})
+			dir := 1
+			if n == database.NullsFirst {
+				dir = -1
+			}
+			sort = append(sort, bson.E{Key: flag, Value: dir})
+			project = append(project, bson.E{Key: flag, Value: 0})
+		}
+		sort = append(sort, e)
+	}
+	if len(flags) == 0 {
+		return []bson.D{{{Key: "$sort", Value: sort}}}
+	}
+	return []bson.D{
+		{{Key: "$addFields", Value: flags}},
+		{{Key: "$sort", Value: sort}},
+		{{Key: "$project", Value: project}},
 	}
-	return res, nil
+}
+
+func convertCollation(c database.Collation) *options.Collation {
+	res := &options.Collation{
+		Locale:          c.Locale,
+		NumericOrdering: c.NumericOrdering,
+	}
+	if res.Locale == "" {
+		res.Locale = "en"
+	}
+	if c.CaseInsensitive {
+		res.Strength = 2
+	}
+	return res
 }
 
 func convertToBson[T any](data T) (bson.D, error) {
@@ -40,7 +197,12 @@ func convertToBson[T any](data T) (bson.D, error) {
 		if e.Required && e.Value == nil {
 			return nil, errors.New("field is required but not provided")
 		}
-		if e.MongoID && e.Value != "" {
+		// an empty id is left out so mongo assigns one on insert and updates
+		// don't try to overwrite the immutable _id
+		if e.MongoID && e.Value == "" {
+			continue
+		}
+		if e.MongoID {
 			id, err := bson.ObjectIDFromHex(e.Value.(string))
 			if err != nil {
 				return nil, err
@@ -56,25 +218,29 @@ func convertToBson[T any](data T) (bson.D, error) {
 func convertFromBson[T any](obj T, doc bson.D) error {
 	var parserModel = database.M{}
 	for _, d := range doc {
-		var val interface{}
-		switch d.Value.(type) {
-		case bson.ObjectID:
-			val = d.Value.(bson.ObjectID).Hex()
-		case bson.DateTime:
-			t := d.Value.(bson.DateTime).Time()
-			val = t
-		case bson.Binary:
-			t, ok := d.Value.(bson.Binary)
-			if !ok {
-				return errors.New("invalid doc provided")
-			}
-			if t.Subtype == 4 {
-				val, _ = uuid.FromBytes(t.Data)
-			}
-		default:
-			val = d.Value
+		val, err := convertBsonValue(d.Value)
+		if err != nil {
+			return err
 		}
 		parserModel = append(parserModel, database.P{Key: d.Key, Value: val})
 	}
 	return database.DecodeModel(obj, parserModel)
 }
+
+// convertBsonValue maps the driver specific types onto the plain Go values the
+// database package decodes into struct fields.
+func convertBsonValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bson.ObjectID:
+		return v.Hex(), nil
+	case bson.DateTime:
+		return v.Time(), nil
+	case bson.Binary:
+		if v.Subtype == uuidSubtype {
+			return uuid.FromBytes(v.Data)
+		}
+		return nil, nil
+	default:
+		return value, nil
+	}
+}
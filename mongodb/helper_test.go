@@ -3,86 +3,180 @@ package mongodb
 import (
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/neghi-go/database"
 
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
-func Test_getIndexes(t *testing.T) {
-	type args struct {
-		model interface{}
-	}
+func Test_indexModel(t *testing.T) {
 	tests := []struct {
-		name    string
-		args    args
-		want    []mongo.IndexModel
-		wantErr bool
+		name     string
+		spec     database.IndexSpec
+		wantKeys bson.D
+		want     options.IndexOptions
+		wantErr  bool
 	}{
 		{
-			name: "Test With Index Property",
-			args: args{
-				model: struct {
-					Name string `db:"name,index"`
-				}{},
+			name: "Test Compound Index",
+			spec: database.IndexSpec{
+				Keys:   []database.IndexKey{{Field: "org", Kind: database.IndexAsc}, {Field: "created_at", Kind: database.IndexDesc}},
+				Unique: true,
 			},
-			want: []mongo.IndexModel{
-				{
-					Keys:    bson.D{{Key: "name", Value: 1}},
-					Options: options.Index().SetUnique(false),
-				},
+			wantKeys: bson.D{{Key: "org", Value: 1}, {Key: "created_at", Value: -1}},
+			want:     options.IndexOptions{Unique: ptr(true)},
+		},
+		{
+			name: "Test TTL Index",
+			spec: database.IndexSpec{
+				Keys:        []database.IndexKey{{Field: "expires", Kind: database.IndexAsc}},
+				ExpireAfter: ptr(24 * time.Hour),
 			},
-			wantErr: false,
+			wantKeys: bson.D{{Key: "expires", Value: 1}},
+			want:     options.IndexOptions{Unique: ptr(false), ExpireAfterSeconds: ptr(int32(86400))},
 		},
 		{
-			name: "Test With Index and Unique Property",
-			args: args{
-				model: struct {
-					Name string `db:"name,index,unique"`
-				}{},
+			name: "Test TTL Index At Field Time",
+			spec: database.IndexSpec{
+				Keys:        []database.IndexKey{{Field: "expires", Kind: database.IndexAsc}},
+				ExpireAfter: ptr(time.Duration(0)),
 			},
-			want: []mongo.IndexModel{
-				{
-					Keys:    bson.D{{Key: "name", Value: 1}},
-					Options: options.Index().SetUnique(true),
-				},
+			wantKeys: bson.D{{Key: "expires", Value: 1}},
+			want:     options.IndexOptions{Unique: ptr(false), ExpireAfterSeconds: ptr(int32(0))},
+		},
+		{
+			name: "Test Text Index",
+			spec: database.IndexSpec{
+				Keys:    []database.IndexKey{{Field: "title", Kind: database.IndexText}, {Field: "body", Kind: database.IndexText}},
+				Weights: map[string]int32{"title": 10, "body": 1},
+			},
+			wantKeys: bson.D{{Key: "title", Value: "text"}, {Key: "body", Value: "text"}},
+			want: options.IndexOptions{
+				Unique:  ptr(false),
+				Weights: bson.D{{Key: "body", Value: int32(1)}, {Key: "title", Value: int32(10)}},
 			},
-			wantErr: false,
 		},
 		{
-			name: "Test with Keyed Property",
-			args: args{
-				model: struct {
-					Name string `db:"name"`
-				}{},
+			name: "Test Partial Sparse Collated Index",
+			spec: database.IndexSpec{
+				Keys:      []database.IndexKey{{Field: "email", Kind: database.IndexAsc}},
+				Sparse:    true,
+				Partial:   map[string]interface{}{"active": true},
+				Collation: &database.Collation{CaseInsensitive: true},
+			},
+			wantKeys: bson.D{{Key: "email", Value: 1}},
+			want: options.IndexOptions{
+				Unique:                  ptr(false),
+				Sparse:                  ptr(true),
+				PartialFilterExpression: bson.D{{Key: "active", Value: true}},
+				Collation:               &options.Collation{Locale: "en", Strength: 2},
 			},
-			want:    []mongo.IndexModel{},
-			wantErr: false,
 		},
 		{
-			name: "Test with MongoID Property",
-			args: args{
-				model: struct {
-					ID string `db:"index,mongoid"`
-				}{},
+			name: "Test Hashed Index",
+			spec: database.IndexSpec{
+				Keys: []database.IndexKey{{Field: "session", Kind: database.IndexHashed}},
 			},
-			want:    []mongo.IndexModel{},
+			wantKeys: bson.D{{Key: "session", Value: "hashed"}},
+			want:     options.IndexOptions{Unique: ptr(false)},
+		},
+		{
+			name:    "Test Without Keys",
+			spec:    database.IndexSpec{},
 			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := getIndexes(tt.args.model)
+			got, err := indexModel(tt.spec)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("getIndexes() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("indexModel() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
 				return
 			}
-			require.IsType(t, tt.want, got)
+			require.Equal(t, tt.wantKeys, got.Keys)
+			var opts options.IndexOptions
+			for _, set := range got.Options.Opts {
+				require.NoError(t, set(&opts))
+			}
+			require.Equal(t, tt.want, opts)
 		})
 	}
 }
 
+func Test_diffIndexes(t *testing.T) {
+	expire := int64(3600)
+	existing := []existingIndex{
+		{Name: "_id_", Key: bson.D{{Key: "_id", Value: int32(1)}}},
+		{Name: "email_1", Key: bson.D{{Key: "email", Value: int32(1)}}},
+		{Name: "name_1", Key: bson.D{{Key: "name", Value: int32(1)}}},
+		{Name: "expires_1", Key: bson.D{{Key: "expires", Value: int32(1)}}, Expire: &expire},
+		{
+			Name:    "title_text_body_text",
+			Key:     bson.D{{Key: "_fts", Value: "text"}, {Key: "_ftsx", Value: int32(1)}},
+			Weights: bson.D{{Key: "body", Value: int32(1)}, {Key: "title", Value: int32(10)}},
+		},
+	}
+	specs := []database.IndexSpec{
+		{Keys: []database.IndexKey{{Field: "email", Kind: database.IndexAsc}}, Unique: true},
+		{Keys: []database.IndexKey{{Field: "expires", Kind: database.IndexAsc}}, ExpireAfter: ptr(time.Hour)},
+		{
+			Keys:    []database.IndexKey{{Field: "title", Kind: database.IndexText}, {Field: "body", Kind: database.IndexText}},
+			Weights: map[string]int32{"title": 10},
+		},
+		{Keys: []database.IndexKey{{Field: "org", Kind: database.IndexAsc}, {Field: "created_at", Kind: database.IndexDesc}}},
+	}
+
+	got, err := diffIndexes(existing, specs)
+	require.NoError(t, err)
+	require.Equal(t, []IndexChange{
+		{Action: IndexRebuild, Name: "email_1", Spec: specs[0], Reason: "unique changed"},
+		{Action: IndexCreate, Name: "org_1_created_at_-1", Spec: specs[3]},
+		{Action: IndexDrop, Name: "name_1", Reason: "not declared"},
+	}, got)
+
+	t.Run("Test Zero Expiry", func(t *testing.T) {
+		zero := int64(0)
+		spec := database.IndexSpec{Keys: []database.IndexKey{{Field: "expires", Kind: database.IndexAsc}}, ExpireAfter: ptr(time.Duration(0))}
+		got, err := diffIndexes([]existingIndex{{Name: "expires_1", Key: bson.D{{Key: "expires", Value: int32(1)}}, Expire: &zero}}, []database.IndexSpec{spec})
+		require.NoError(t, err)
+		require.Empty(t, got)
+
+		got, err = diffIndexes([]existingIndex{{Name: "expires_1", Key: bson.D{{Key: "expires", Value: int32(1)}}}}, []database.IndexSpec{spec})
+		require.NoError(t, err)
+		require.Equal(t, []IndexChange{{Action: IndexRebuild, Name: "expires_1", Spec: spec, Reason: "expiry changed"}}, got)
+	})
+
+	t.Run("Test Duplicate Declaration", func(t *testing.T) {
+		_, err := diffIndexes(nil, []database.IndexSpec{specs[0], specs[0]})
+		require.Error(t, err)
+	})
+}
+
+func TestIndexName(t *testing.T) {
+	require.Equal(t, "email_1", IndexName(database.IndexSpec{
+		Keys: []database.IndexKey{{Field: "email", Kind: database.IndexAsc}},
+	}))
+	require.Equal(t, "org_1_created_at_-1_session_hashed", IndexName(database.IndexSpec{
+		Keys: []database.IndexKey{
+			{Field: "org", Kind: database.IndexAsc},
+			{Field: "created_at", Kind: database.IndexDesc},
+			{Field: "session", Kind: database.IndexHashed},
+		},
+	}))
+	require.Equal(t, "custom", IndexName(database.IndexSpec{Name: "custom"}))
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
 func Test_convertToBson(t *testing.T) {
 	id, _ := bson.ObjectIDFromHex("677904ef31ac7ccf730d4e39")
 	type args struct {
@@ -175,3 +269,134 @@ func Test_convertFromBson(t *testing.T) {
 		})
 	}
 }
+
+func Test_convertBsonValue(t *testing.T) {
+	id, _ := bson.ObjectIDFromHex("677904ef31ac7ccf730d4e39")
+	uid := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	now := time.UnixMilli(time.Now().UnixMilli())
+	tests := []struct {
+		name  string
+		value interface{}
+		want  interface{}
+	}{
+		{
+			name:  "Test ObjectID",
+			value: id,
+			want:  "677904ef31ac7ccf730d4e39",
+		},
+		{
+			name:  "Test DateTime",
+			value: bson.NewDateTimeFromTime(now),
+			want:  now,
+		},
+		{
+			name:  "Test UUID",
+			value: bson.Binary{Subtype: uuidSubtype, Data: uid[:]},
+			want:  uid,
+		},
+		{
+			name:  "Test Plain Value",
+			value: "Jon Doe",
+			want:  "Jon Doe",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := convertBsonValue(tt.value)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_sortValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		order   database.OrderType
+		want    int
+		wantErr bool
+	}{
+		{
+			name:  "Test Ascending",
+			order: database.ASC,
+			want:  1,
+		},
+		{
+			name:  "Test Descending",
+			order: database.DESC,
+			want:  -1,
+		},
+		{
+			name:    "Test Unknown",
+			order:   database.OrderType(7),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sortValue(tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("sortValue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_sortStages(t *testing.T) {
+	order := bson.D{{Key: "score", Value: 1}, {Key: "name", Value: -1}}
+	t.Run("Test Native Nulls", func(t *testing.T) {
+		got := sortStages(order, nil)
+		require.Equal(t, []bson.D{{{Key: "$sort", Value: order}}}, got)
+	})
+	t.Run("Test Nulls Last", func(t *testing.T) {
+		got := sortStages(order, map[string]database.NullsOrder{"score": database.NullsLast})
+		require.Len(t, got, 3)
+		require.Equal(t, bson.D{{Key: "$sort", Value: bson.D{
+			{Key: "_nulls_score", Value: 1},
+			{Key: "score", Value: 1},
+			{Key: "name", Value: -1},
+		}}}, got[1])
+		require.Equal(t, bson.D{{Key: "$project", Value: bson.D{{Key: "_nulls_score", Value: 0}}}}, got[2])
+	})
+}
+
+func Test_querySummary(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter bson.D
+		want   string
+	}{
+		{
+			name: "Test Empty Filter",
+			want: "{}",
+		},
+		{
+			name:   "Test Equality",
+			filter: bson.D{{Key: "email", Value: "jane@example.com"}},
+			want:   `{"email":"?"}`,
+		},
+		{
+			name: "Test Operators",
+			filter: bson.D{
+				{Key: "age", Value: bson.M{"$lt": 30, "$gte": 18}},
+				{Key: "$or", Value: bson.A{bson.D{{Key: "role", Value: "admin"}}, bson.D{{Key: "tags", Value: bson.D{{Key: "$in", Value: bson.A{"a", "b"}}}}}}},
+			},
+			want: `{"age":{"$gte":"?","$lt":"?"},"$or":[{"role":"?"},{"tags":{"$in":["?","?"]}}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, querySummary(tt.filter))
+		})
+	}
+}
+
+func Test_filterValue(t *testing.T) {
+	id := bson.NewObjectID()
+	require.Equal(t, id, filterValue("_id", id.Hex()))
+	require.Equal(t, id, filterValue("_id", id))
+	require.Equal(t, "not-hex", filterValue("_id", "not-hex"))
+	require.Equal(t, id.Hex(), filterValue("ref", id.Hex()))
+}
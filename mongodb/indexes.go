@@ -0,0 +1,286 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/neghi-go/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// IndexAction is the change needed to bring an index in line with its
+// declaration.
+type IndexAction string
+
+const (
+	IndexCreate  IndexAction = "create"
+	IndexDrop    IndexAction = "drop"
+	IndexRebuild IndexAction = "rebuild"
+)
+
+// IndexMode selects whether ReconcileIndexes only reports changes or also
+// applies them.
+type IndexMode int
+
+const (
+	IndexDryRun IndexMode = iota
+	IndexApply
+)
+
+// IndexChange is a single step of an index reconciliation plan. Spec is the
+// declared index and is empty for drops.
+type IndexChange struct {
+	Action IndexAction
+	Name   string
+	Spec   database.IndexSpec
+	Reason string
+}
+
+func (c IndexChange) String() string {
+	if c.Reason == "" {
+		return fmt.Sprintf("%s %s", c.Action, c.Name)
+	}
+	return fmt.Sprintf("%s %s (%s)", c.Action, c.Name, c.Reason)
+}
+
+// existingIndex is the subset of listIndexes output compared against the
+// declared specs.
+type existingIndex struct {
+	Name      string   `bson:"name"`
+	Key       bson.D   `bson:"key"`
+	Unique    bool     `bson:"unique"`
+	Sparse    bool     `bson:"sparse"`
+	Expire    *int64   `bson:"expireAfterSeconds"`
+	Weights   bson.D   `bson:"weights"`
+	Partial   bson.Raw `bson:"partialFilterExpression"`
+	Collation *struct {
+		Locale          string `bson:"locale"`
+		Strength        int    `bson:"strength"`
+		NumericOrdering bool   `bson:"numericOrdering"`
+	} `bson:"collation"`
+}
+
+// IndexName returns the name an index is created under: the explicit name if
+// the spec has one, otherwise the field_direction pairs joined by underscores,
+// which is also the name mongo would generate for the same keys.
+func IndexName(spec database.IndexSpec) string {
+	if spec.Name != "" {
+		return spec.Name
+	}
+	var parts []string
+	for _, k := range spec.Keys {
+		parts = append(parts, k.Field, indexKeyValue(k.Kind))
+	}
+	return strings.Join(parts, "_")
+}
+
+func indexKeyValue(kind database.IndexKind) string {
+	switch kind {
+	case database.IndexDesc:
+		return "-1"
+	case database.IndexText, database.IndexHashed:
+		return string(kind)
+	default:
+		return "1"
+	}
+}
+
+// ReconcileIndexes compares the indexes on coll with specs and returns the
+// drops, creates and rebuilds needed to match them. Indexes that are not
+// declared, other than the _id index, are reported as stale drops. In
+// IndexApply mode the plan is also executed, drops first.
+func ReconcileIndexes(ctx context.Context, conn *mongoDatabase, coll string, specs []database.IndexSpec, mode IndexMode) ([]IndexChange, error) {
+	col := conn.db.Collection(coll)
+
+	result, err := col.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var existing []existingIndex
+	if err := result.All(ctx, &existing); err != nil {
+		return nil, err
+	}
+
+	changes, err := diffIndexes(existing, specs)
+	if err != nil {
+		return nil, err
+	}
+	if mode == IndexApply {
+		if err := applyIndexChanges(ctx, conn, coll, changes); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+func applyIndexChanges(ctx context.Context, conn *mongoDatabase, coll string, changes []IndexChange) error {
+	col := conn.db.Collection(coll)
+	for _, c := range changes {
+		if c.Action == IndexDrop || c.Action == IndexRebuild {
+			if err := col.Indexes().DropOne(ctx, c.Name); err != nil {
+				return err
+			}
+		}
+	}
+	for _, c := range changes {
+		if c.Action == IndexCreate || c.Action == IndexRebuild {
+			spec := c.Spec
+			spec.Name = c.Name
+			index, err := indexModel(spec)
+			if err != nil {
+				return err
+			}
+			if _, err := col.Indexes().CreateOne(ctx, index); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func diffIndexes(existing []existingIndex, specs []database.IndexSpec) ([]IndexChange, error) {
+	current := map[string]existingIndex{}
+	for _, e := range existing {
+		current[e.Name] = e
+	}
+
+	var changes []IndexChange
+	declared := map[string]bool{}
+	for _, spec := range specs {
+		name := IndexName(spec)
+		if declared[name] {
+			return nil, fmt.Errorf("index %s is declared more than once", name)
+		}
+		declared[name] = true
+
+		e, ok := current[name]
+		if !ok {
+			changes = append(changes, IndexChange{Action: IndexCreate, Name: name, Spec: spec})
+			continue
+		}
+		reason, err := indexDrift(e, spec)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			changes = append(changes, IndexChange{Action: IndexRebuild, Name: name, Spec: spec, Reason: reason})
+		}
+	}
+
+	for _, e := range existing {
+		if e.Name == "_id_" || declared[e.Name] {
+			continue
+		}
+		changes = append(changes, IndexChange{Action: IndexDrop, Name: e.Name, Reason: "not declared"})
+	}
+	return changes, nil
+}
+
+// indexDrift describes the first difference between an existing index and its
+// declaration, or returns an empty string when they match.
+func indexDrift(e existingIndex, spec database.IndexSpec) (string, error) {
+	text := map[string]int32{}
+	var keys []string
+	for _, k := range spec.Keys {
+		if k.Kind == database.IndexText {
+			text[k.Field] = 1
+			continue
+		}
+		keys = append(keys, k.Field+":"+indexKeyValue(k.Kind))
+	}
+	// text keys are stored as _fts/_ftsx with the fields moved into weights
+	var existingKeys []string
+	for _, k := range e.Key {
+		if k.Key == "_fts" || k.Key == "_ftsx" {
+			continue
+		}
+		existingKeys = append(existingKeys, fmt.Sprintf("%s:%v", k.Key, k.Value))
+	}
+	if !slices.Equal(keys, existingKeys) {
+		return "keys changed", nil
+	}
+
+	if len(text) > 0 {
+		maps.Copy(text, spec.Weights)
+		weights := map[string]int32{}
+		for _, w := range e.Weights {
+			weights[w.Key] = int32(toFloat(w.Value))
+		}
+		if !maps.Equal(text, weights) {
+			return "weights changed", nil
+		}
+	}
+
+	if e.Unique != spec.Unique {
+		return "unique changed", nil
+	}
+	if e.Sparse != spec.Sparse {
+		return "sparse changed", nil
+	}
+
+	var expire int64 = -1
+	if e.Expire != nil {
+		expire = *e.Expire
+	}
+	want := int64(-1)
+	if spec.ExpireAfter != nil {
+		want = int64(*spec.ExpireAfter / time.Second)
+	}
+	if expire != want {
+		return "expiry changed", nil
+	}
+
+	partial := ""
+	if len(spec.Partial) > 0 {
+		b, err := bson.MarshalExtJSON(sortedDoc(spec.Partial), false, false)
+		if err != nil {
+			return "", err
+		}
+		partial = string(b)
+	}
+	existingPartial := ""
+	if len(e.Partial) > 0 {
+		b, err := bson.MarshalExtJSON(e.Partial, false, false)
+		if err != nil {
+			return "", err
+		}
+		existingPartial = string(b)
+	}
+	if partial != existingPartial {
+		return "partial filter changed", nil
+	}
+
+	collation, existingCollation := "", ""
+	if spec.Collation != nil {
+		c := convertCollation(*spec.Collation)
+		strength := c.Strength
+		if strength == 0 {
+			strength = 3
+		}
+		collation = fmt.Sprintf("%s/%d/%t", c.Locale, strength, c.NumericOrdering)
+	}
+	if e.Collation != nil {
+		existingCollation = fmt.Sprintf("%s/%d/%t", e.Collation.Locale, e.Collation.Strength, e.Collation.NumericOrdering)
+	}
+	if collation != existingCollation {
+		return "collation changed", nil
+	}
+	return "", nil
+}
+
+func toFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		return 0
+	}
+}
@@ -3,6 +3,12 @@ package mongodb
 import (
 	"context"
 	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
 	"time"
 
 	"github.com/neghi-go/database"
@@ -12,120 +18,364 @@ import (
 )
 
 type MongoModel[T any] struct {
-	ctx    context.Context
-	filter bson.D
-	order  bson.D
-	limit  int64
-	offset int64
-	client *mongo.Collection
+	ctx       context.Context
+	filter    bson.D
+	order     bson.D
+	nulls     map[string]database.NullsOrder
+	collation *options.Collation
+	limit     int64
+	offset    int64
+	batch     int32
+	client    *mongo.Collection
+
+	conn         *mongoDatabase
+	interceptors []database.Interceptor
+
+	fields    []database.Field
+	update    bson.D
+	updateErr error
+
+	relations []database.Relation
+	preload   []database.Relation
+
+	// tenant is the key of the tenant field, empty when the model has none
+	tenant string
+	scopes *database.Scopes
+
+	// queryErr is the first invalid param of the current query, reported
+	// when it runs
+	queryErr error
 }
 
+var (
+	_ database.Binder[struct{}] = (*MongoModel[struct{}])(nil)
+	_ database.Identifier       = (*MongoModel[struct{}])(nil)
+)
+
 // All implements database.Query.
 func (m *MongoModel[T]) All() ([]*T, error) {
 	var res []*T
-	result, err := m.client.Find(m.ctx, m.filter, options.Find().
-		SetLimit(m.limit).SetSkip(m.offset).SetSort(m.order))
-	if err != nil {
-		return nil, err
+	for doc, err := range m.Iter() {
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, doc)
 	}
+	return res, nil
+}
 
-	defer result.Close(m.ctx)
+// Iter implements database.Query.
+func (m *MongoModel[T]) Iter() iter.Seq2[*T, error] {
+	if _, err := m.scope(); err != nil {
+		return func(yield func(*T, error) bool) {
+			yield(nil, err)
+		}
+	}
+	ctx, op, open := m.ctx, m.opInfo(database.OpFind), m.finder()
+	preload, size := m.preloader()
+	m.reset()
 
-	for result.Next(m.ctx) {
-		var single bson.D
-		if err := result.Decode(&single); err != nil {
-			return nil, err
+	return func(yield func(*T, error) bool) {
+		stopped := false
+		// the operation is timed per batch, from reading it off the cursor to
+		// handing it to the consumer, so the time the consumer spends on the
+		// documents is left out. The sum replaces the duration of the whole
+		// loop before any interceptor reads it
+		var busy time.Duration
+		timed := func(ctx context.Context, op *database.OpInfo, next func(context.Context) error) error {
+			err := next(ctx)
+			op.Duration = busy
+			return err
 		}
-		var singleRes T
-		if err := convertFromBson(&singleRes, single); err != nil {
-			return nil, err
+		err := m.intercept(ctx, op, func(ctx context.Context) error {
+			start := time.Now()
+			defer func() { busy += time.Since(start) }()
+
+			result, err := open(ctx)
+			if err != nil {
+				return err
+			}
+			// the cursor must be released even when ctx is what stopped iteration
+			defer result.Close(context.WithoutCancel(ctx))
+
+			// documents are held back until enough of them are read to
+			// preload their relations together
+			var batch []*T
+			emit := func() error {
+				if err := preload(ctx, batch); err != nil {
+					return err
+				}
+				busy += time.Since(start)
+				defer func() { start = time.Now() }()
+				for _, doc := range batch {
+					if !yield(doc, nil) {
+						stopped = true
+						return nil
+					}
+				}
+				batch = batch[:0]
+				return nil
+			}
+			for result.Next(ctx) {
+				var single bson.D
+				if err := result.Decode(&single); err != nil {
+					return err
+				}
+				var singleRes T
+				if err := convertFromBson(&singleRes, single); err != nil {
+					return err
+				}
+				op.Docs++
+				if batch = append(batch, &singleRes); len(batch) < size {
+					continue
+				}
+				if err := emit(); err != nil || stopped {
+					return err
+				}
+			}
+			if err := result.Err(); err != nil {
+				return err
+			}
+			return emit()
+		}, timed)
+		if err != nil && !stopped {
+			yield(nil, err)
 		}
-		res = append(res, &singleRes)
 	}
-	m.reset()
-	return res, nil
+}
+
+// Each implements database.Query.
+func (m *MongoModel[T]) Each(fn func(*T) error) error {
+	for doc, err := range m.Iter() {
+		if err != nil {
+			return err
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Count implements database.Query.
 func (m *MongoModel[T]) Count() (int64, error) {
-	count, err := m.client.CountDocuments(m.ctx, m.filter, options.Count().SetLimit(m.limit).
-		SetSkip(m.offset))
-	if err != nil {
+	if _, err := m.scope(); err != nil {
 		return 0, err
 	}
+	ctx, op, filter := m.ctx, m.opInfo(database.OpCount), m.filter
+	opts := options.Count().SetLimit(m.limit).SetSkip(m.offset).SetCollation(m.collation)
 	m.reset()
+
+	var count int64
+	err := m.intercept(ctx, op, func(ctx context.Context) (err error) {
+		count, err = m.client.CountDocuments(ctx, filter, opts)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
 	return count, nil
 }
 
 // Delete implements database.Query.
 func (m *MongoModel[T]) Delete() error {
-	_, err := m.client.DeleteOne(m.ctx, m.filter)
-	if err != nil {
+	if _, err := m.scope(); err != nil {
 		return err
 	}
+	ctx, op, filter := m.ctx, m.opInfo(database.OpDelete), m.filter
+	opts := options.DeleteOne().SetCollation(m.collation)
 	m.reset()
-	return nil
+
+	return m.intercept(ctx, op, func(ctx context.Context) error {
+		result, err := m.client.DeleteOne(ctx, filter, opts)
+		if err != nil {
+			return err
+		}
+		op.Docs = result.DeletedCount
+		return nil
+	})
 }
 
 // DeleteMany implements database.Query.
 func (m *MongoModel[T]) DeleteMany() error {
-	_, err := m.client.DeleteMany(m.ctx, m.filter)
-	if err != nil {
+	if _, err := m.scope(); err != nil {
 		return err
 	}
+	ctx, op, filter := m.ctx, m.opInfo(database.OpDeleteMany), m.filter
+	opts := options.DeleteMany().SetCollation(m.collation)
 	m.reset()
-	return nil
+
+	return m.intercept(ctx, op, func(ctx context.Context) error {
+		result, err := m.client.DeleteMany(ctx, filter, opts)
+		if err != nil {
+			return err
+		}
+		op.Docs = result.DeletedCount
+		return nil
+	})
 }
 
-// First implements database.Query.
-func (m *MongoModel[T]) First() (*T, error) {
-	var res T
-	result := m.client.FindOne(m.ctx, m.filter, options.FindOne().
-		SetSort(m.order))
+// Distinct implements database.Query.
+func (m *MongoModel[T]) Distinct(field string) ([]any, error) {
+	if _, err := m.scope(); err != nil {
+		return nil, err
+	}
+	ctx, op, filter := m.ctx, m.opInfo(database.OpDistinct), m.filter
+	opts := options.Distinct().SetCollation(m.collation)
+	m.reset()
 
-	var single bson.D
-	if err := result.Decode(&single); err != nil {
+	var values bson.A
+	err := m.intercept(ctx, op, func(ctx context.Context) error {
+		if err := m.client.Distinct(ctx, field, filter, opts).Decode(&values); err != nil {
+			return err
+		}
+		op.Docs = int64(len(values))
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
-	if err := convertFromBson(&res, single); err != nil {
+	res := make([]any, 0, len(values))
+	for _, v := range values {
+		val, err := convertBsonValue(v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, val)
+	}
+	return res, nil
+}
+
+// First implements database.Query.
+func (m *MongoModel[T]) First() (*T, error) {
+	if _, err := m.scope(); err != nil {
 		return nil, err
 	}
+	m.limit = 1
+	ctx, op, open := m.ctx, m.opInfo(database.OpFindOne), m.finder()
+	preload, _ := m.preloader()
 	m.reset()
+
+	var res T
+	err := m.intercept(ctx, op, func(ctx context.Context) error {
+		result, err := open(ctx)
+		if err != nil {
+			return err
+		}
+		defer result.Close(ctx)
+
+		if !result.Next(ctx) {
+			if err := result.Err(); err != nil {
+				return err
+			}
+			return mongo.ErrNoDocuments
+		}
+		var single bson.D
+		if err := result.Decode(&single); err != nil {
+			return err
+		}
+		op.Docs = 1
+		if err := convertFromBson(&res, single); err != nil {
+			return err
+		}
+		return preload(ctx, []*T{&res})
+	})
+	if err != nil {
+		return nil, err
+	}
 	return &res, nil
 }
 
 // Update implements database.Query.
 func (m *MongoModel[T]) Update(doc T) error {
-	d, err := convertToBson(doc)
+	tenant, err := m.scope()
 	if err != nil {
 		return err
 	}
-	result, err := m.client.UpdateOne(m.ctx, m.filter, bson.D{{Key: "$set", Value: d}})
+	ctx, op, filter := m.ctx, m.opInfo(database.OpUpdate), m.filter
+	opts := options.UpdateOne().SetCollation(m.collation)
+	m.reset()
+
+	d, err := convertToBson(doc)
 	if err != nil {
 		return err
 	}
-	if result.MatchedCount < 0 {
-		return errors.New("error updating document")
-	}
-	m.reset()
-	return nil
+	d = m.stamp(d, tenant)
+	return m.intercept(ctx, op, func(ctx context.Context) error {
+		result, err := m.client.UpdateOne(ctx, filter, bson.D{{Key: "$set", Value: d}}, opts)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount < 0 {
+			return errors.New("error updating document")
+		}
+		op.Docs = result.MatchedCount
+		return nil
+	})
 }
 
 // UpdateMany implements database.Query.
 func (m *MongoModel[T]) UpdateMany(doc T) error {
-	d, err := convertToBson(doc)
+	tenant, err := m.scope()
 	if err != nil {
 		return err
 	}
-	result, err := m.client.UpdateMany(m.ctx, m.filter, bson.D{{Key: "$set", Value: d}})
+	ctx, op, filter := m.ctx, m.opInfo(database.OpUpdateMany), m.filter
+	opts := options.UpdateMany().SetCollation(m.collation)
+	m.reset()
+
+	d, err := convertToBson(doc)
 	if err != nil {
 		return err
 	}
-	if result.MatchedCount < 0 {
-		return errors.New("error updating documents")
+	d = m.stamp(d, tenant)
+	return m.intercept(ctx, op, func(ctx context.Context) error {
+		result, err := m.client.UpdateMany(ctx, filter, bson.D{{Key: "$set", Value: d}}, opts)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount < 0 {
+			return errors.New("error updating documents")
+		}
+		op.Docs = result.MatchedCount
+		return nil
+	})
+}
+
+// UpdateFields implements database.Query.
+func (m *MongoModel[T]) UpdateFields(doc T, fields ...string) error {
+	tenant, err := m.scope()
+	if err != nil {
+		return err
 	}
+	ctx, op, filter := m.ctx, m.opInfo(database.OpUpdate), m.filter
+	opts := options.UpdateOne().SetCollation(m.collation)
 	m.reset()
-	return nil
+
+	if len(fields) == 0 {
+		return nil
+	}
+	d, err := convertToBson(doc)
+	if err != nil {
+		return err
+	}
+	d = m.stamp(d, tenant)
+	set := bson.D{}
+	for _, field := range fields {
+		i := slices.IndexFunc(d, func(e bson.E) bool { return e.Key == field })
+		if i < 0 {
+			return fmt.Errorf("error: unknown field %s", field)
+		}
+		set = append(set, d[i])
+	}
+	return m.intercept(ctx, op, func(ctx context.Context) error {
+		result, err := m.client.UpdateOne(ctx, filter, bson.D{{Key: "$set", Value: set}}, opts)
+		if err != nil {
+			return err
+		}
+		op.Docs = result.MatchedCount
+		return nil
+	})
 }
 
 // ExecRaw implements database.Store.
@@ -135,6 +385,10 @@ func (m *MongoModel[T]) ExecRaw() error {
 
 // Query implements database.Store.
 func (m *MongoModel[T]) Query(query_params ...database.Params) database.Query[T] {
+	query_params, err := m.scopes.Expand(query_params)
+	if err != nil && m.queryErr == nil {
+		m.queryErr = err
+	}
 	var q_params []database.QueryStruct
 
 	for _, param := range query_params {
@@ -148,22 +402,28 @@ func (m *MongoModel[T]) Query(query_params ...database.Params) database.Query[T]
 			if !ok {
 				panic(errors.New("unsupported"))
 			}
-			m.filter = append(m.filter, bson.E{Key: val.Key(), Value: val.Value()})
+			value, err := database.FilterValue(m.fields, val.Key(), val.Value())
+			if err != nil && m.queryErr == nil {
+				m.queryErr = err
+			}
+			m.filter = append(m.filter, bson.E{Key: val.Key(), Value: filterValue(val.Key(), value)})
 		case database.QuerySort:
-			var val int
-			order_val, ok := qq.Value().(database.OrderStruct)
-			if !ok {
+			switch order_val := qq.Value().(type) {
+			case database.OrderStruct:
+				m.addOrder(order_val)
+			case []database.OrderStruct:
+				for _, o := range order_val {
+					m.addOrder(o)
+				}
+			default:
 				panic(errors.New("unsupported"))
 			}
-			switch order_val.Value() {
-			case database.ASC:
-				val = -1
-			case database.DESC:
-				val = 1
-			default:
+		case database.QueryCollation:
+			val, ok := qq.Value().(database.Collation)
+			if !ok {
 				panic(errors.New("unsupported"))
 			}
-			m.order = append(m.order, bson.E{Key: order_val.Key(), Value: val})
+			m.collation = convertCollation(val)
 		case database.QueryLimit:
 			val, ok := qq.Value().(int64)
 			if !ok {
@@ -174,6 +434,27 @@ func (m *MongoModel[T]) Query(query_params ...database.Params) database.Query[T]
 			if !ok {
 			}
 			m.offset = val
+		case database.QueryBatch:
+			val, ok := qq.Value().(int32)
+			if !ok {
+				panic(errors.New("unsupported"))
+			}
+			m.batch = val
+		case database.QueryPreload:
+			val, ok := qq.Value().([]string)
+			if !ok {
+				panic(errors.New("unsupported"))
+			}
+			for _, name := range val {
+				r, ok := database.LookupRelation(m.relations, name)
+				if !ok {
+					if m.queryErr == nil {
+						m.queryErr = fmt.Errorf("error: unknown relation %s", name)
+					}
+					continue
+				}
+				m.preload = append(m.preload, r)
+			}
 		default:
 			panic(errors.New("unsupported"))
 		}
@@ -183,17 +464,40 @@ func (m *MongoModel[T]) Query(query_params ...database.Params) database.Query[T]
 
 // Save implements database.Store.
 func (m *MongoModel[T]) Save(doc ...T) error {
-	for _, d := range doc {
-		v, err := convertToBson(d)
-		if err != nil {
-			return err
-		}
-		_, err = m.client.InsertOne(m.ctx, v)
-		if err != nil {
-			return err
-		}
+	tenant, err := m.tenantOf(m.ctx)
+	if err != nil {
+		return err
 	}
-	return nil
+	op := m.opInfo(database.OpInsert)
+	op.Filter, op.Sort, op.Query = nil, nil, ""
+
+	return m.intercept(m.ctx, op, func(ctx context.Context) error {
+		for _, d := range doc {
+			v, err := convertToBson(d)
+			if err != nil {
+				return err
+			}
+			v = m.stamp(v, tenant)
+			_, err = m.client.InsertOne(ctx, v)
+			if err != nil {
+				return err
+			}
+			op.Docs++
+		}
+		return nil
+	})
+}
+
+// Scope implements database.Model.
+func (m *MongoModel[T]) Scope(name string, params ...database.Params) database.Model[T] {
+	m.scopes.Add(name, params...)
+	return m
+}
+
+// DefaultScope implements database.Model.
+func (m *MongoModel[T]) DefaultScope(params ...database.Params) database.Model[T] {
+	m.scopes.AddDefault(params...)
+	return m
 }
 
 // WithContext implements database.Store.
@@ -202,34 +506,232 @@ func (m *MongoModel[T]) WithContext(ctx context.Context) database.Model[T] {
 	return m
 }
 
+// Bind implements database.Binder. The handle shares the registration of m,
+// scopes included, but none of its query state.
+func (m *MongoModel[T]) Bind(ctx context.Context) database.Model[T] {
+	handle := *m
+	handle.reset()
+	handle.ctx = ctx
+	return &handle
+}
+
+// Identity implements database.Identifier, naming the collection with its
+// database, and the tenant of ctx for tenant scoped models.
+func (m *MongoModel[T]) Identity(ctx context.Context) (string, error) {
+	tenant, err := m.tenantOf(ctx)
+	if err != nil {
+		return "", err
+	}
+	name := m.client.Database().Name() + "." + m.client.Name()
+	if tenant != "" {
+		name += "/" + tenant
+	}
+	return name, nil
+}
+
+// opInfo describes the current query for interceptors. It must be called
+// before the query state is reset.
+func (m *MongoModel[T]) opInfo(operation string) *database.OpInfo {
+	return &database.OpInfo{
+		System:     "mongodb",
+		Database:   m.client.Database().Name(),
+		Collection: m.client.Name(),
+		Operation:  operation,
+		Filter:     m.filter,
+		Sort:       m.order,
+		Limit:      m.limit,
+		Offset:     m.offset,
+		Query:      querySummary(m.filter),
+	}
+}
+
+func (m *MongoModel[T]) intercept(ctx context.Context, op *database.OpInfo, fn func(context.Context) error, inner ...database.Interceptor) error {
+	var chain []database.Interceptor
+	if m.conn != nil {
+		chain = m.conn.chain()
+	}
+	chain = append(chain, m.interceptors...)
+	return database.Intercept(ctx, append(chain, inner...), op, fn)
+}
+
+func (m *MongoModel[T]) addOrder(o database.OrderStruct) {
+	val, err := sortValue(o.Value())
+	if err != nil {
+		panic(err)
+	}
+	m.order = append(m.order, bson.E{Key: o.Key(), Value: val})
+	if o.Nulls() != database.NullsDefault {
+		if m.nulls == nil {
+			m.nulls = map[string]database.NullsOrder{}
+		}
+		m.nulls[o.Key()] = o.Nulls()
+	}
+}
+
+// finder captures the current query and returns a function opening a cursor
+// over it. A find sort cannot place nulls explicitly, so those queries run as
+// an aggregation that sorts on a computed null flag instead.
+func (m *MongoModel[T]) finder() func(ctx context.Context) (*mongo.Cursor, error) {
+	client, filter := m.client, m.filter
+	if len(m.nulls) == 0 {
+		opts := options.Find().SetLimit(m.limit).SetSkip(m.offset).SetSort(m.order).
+			SetCollation(m.collation)
+		if m.batch > 0 {
+			opts.SetBatchSize(m.batch)
+		}
+		return func(ctx context.Context) (*mongo.Cursor, error) {
+			return client.Find(ctx, filter, opts)
+		}
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: filter}}}
+	pipeline = append(pipeline, sortStages(m.order, maps.Clone(m.nulls))...)
+	if m.offset > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: m.offset}})
+	}
+	if m.limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: m.limit}})
+	}
+	opts := options.Aggregate().SetCollation(m.collation)
+	if m.batch > 0 {
+		opts.SetBatchSize(m.batch)
+	}
+	return func(ctx context.Context) (*mongo.Cursor, error) {
+		return client.Aggregate(ctx, pipeline, opts)
+	}
+}
+
 func (m *MongoModel[T]) reset() {
 	m.filter = bson.D{}
 	m.limit = 0
 	m.offset = 0
+	m.batch = 0
 	m.order = bson.D{}
+	m.nulls = nil
+	m.collation = nil
+	m.update = nil
+	m.updateErr = nil
+	m.preload = nil
+	m.queryErr = nil
+}
+
+// RegisterOption configures RegisterModel.
+type RegisterOption func(*registerConfig)
+
+type registerConfig struct {
+	validate     bool
+	level        ValidationLevel
+	action       ValidationAction
+	interceptors []database.Interceptor
+	pending      func(coll string, changes []IndexChange)
+}
+
+// WithInterceptors adds interceptors that run around every operation of the
+// registered model, inside those installed on the database with Use.
+func WithInterceptors(interceptors ...database.Interceptor) RegisterOption {
+	return func(c *registerConfig) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
+
+// WithPendingIndexes receives the index changes registration leaves to an
+// explicit ReconcileIndexes run, the same changes dbctl indexes reports for
+// the collection. Without it they are logged with slog.Default().
+func WithPendingIndexes(fn func(coll string, changes []IndexChange)) RegisterOption {
+	return func(c *registerConfig) {
+		c.pending = fn
+	}
 }
 
-func RegisterModel[T any](conn *mongoDatabase, coll string, model T) (database.Model[T], error) {
+func RegisterModel[T any](conn *mongoDatabase, coll string, model T, opts ...RegisterOption) (database.Model[T], error) {
+	m, err := registerModel(conn, coll, model, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func registerModel[T any](conn *mongoDatabase, coll string, model T, opts ...RegisterOption) (*MongoModel[T], error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 
+	var cfg registerConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	col := conn.db.Collection(coll)
 
-	indexes, err := getIndexes(model)
+	// the validator goes first as creating indexes implicitly creates the
+	// collection without one
+	if cfg.validate {
+		if err := applyValidator(ctx, conn, coll, model, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	fields, err := database.FieldsOf(model)
+	if err != nil {
+		return nil, err
+	}
+	tenant, _, err := database.TenantField(fields)
 	if err != nil {
 		return nil, err
 	}
-	_, err = col.Indexes().CreateMany(ctx, indexes)
+	if tenant.Key != "" && tenant.Type.Kind() != reflect.String {
+		return nil, fmt.Errorf("error: tenant field %s must be a string", tenant.Name)
+	}
+	relations, err := database.RelationsOf(model)
+	if err != nil {
+		return nil, err
+	}
+	specs, err := database.IndexesOf(model)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := ReconcileIndexes(ctx, conn, coll, specs, IndexDryRun)
 	if err != nil {
 		return nil, err
 	}
+	// registration only creates missing indexes. Stale indexes are kept for
+	// instances still running the previous model mid deploy, and rebuilds
+	// leave the collection without the index while it builds again, so both
+	// are reported and left to an explicit ReconcileIndexes run
+	var pending []IndexChange
+	changes = slices.DeleteFunc(changes, func(c IndexChange) bool {
+		if c.Action == IndexCreate {
+			return false
+		}
+		pending = append(pending, c)
+		return true
+	})
+	if len(pending) > 0 {
+		if cfg.pending != nil {
+			cfg.pending(coll, pending)
+		} else {
+			for _, c := range pending {
+				slog.Warn("index change pending, run ReconcileIndexes to apply it",
+					slog.String("collection", coll), slog.String("change", c.String()))
+			}
+		}
+	}
+	if err := applyIndexChanges(ctx, conn, coll, changes); err != nil {
+		return nil, err
+	}
 
 	return &MongoModel[T]{
 		client: col,
+		conn:   conn,
 		ctx:    context.Background(),
 		order:  bson.D{},
 		filter: bson.D{},
 		limit:  0,
 		offset: 0,
+
+		fields:       fields,
+		relations:    relations,
+		tenant:       tenant.Key,
+		scopes:       &database.Scopes{},
+		interceptors: cfg.interceptors,
 	}, nil
 }
@@ -1,24 +1,34 @@
 package mongodb
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/neghi-go/database"
+	"github.com/neghi-go/database/databasetest"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 var test_url string
 
 func TestMain(m *testing.M) {
+	// a single node replica set, as change streams and transactions need one
 	client := testcontainers.ContainerRequest{
 		Image:        "mongo:8.0",
 		ExposedPorts: []string{"27017/tcp"},
+		Cmd:          []string{"--replSet", "rs0", "--bind_ip_all"},
+		WaitingFor:   wait.ForLog("Waiting for connections"),
 	}
 	mongoClient, err := testcontainers.GenericContainer(context.Background(), testcontainers.GenericContainerRequest{
 		ContainerRequest: client,
@@ -28,8 +38,17 @@ func TestMain(m *testing.M) {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	code, _, err := mongoClient.Exec(context.Background(), []string{"mongosh", "--quiet", "--eval",
+		"rs.initiate(); while (!db.hello().isWritablePrimary) { sleep(100) }"})
+	if err != nil || code != 0 {
+		fmt.Println("replica set initiation failed:", code, err)
+		testcontainers.TerminateContainer(mongoClient)
+		os.Exit(1)
+	}
 
-	test_url, _ = mongoClient.Endpoint(context.Background(), "")
+	endpoint, _ := mongoClient.Endpoint(context.Background(), "")
+	// the member is known by its container hostname, unreachable from here
+	test_url = endpoint + "/?directConnection=true"
 	exitVal := m.Run()
 	testcontainers.TerminateContainer(mongoClient)
 	os.Exit(exitVal)
@@ -185,8 +204,801 @@ func TestModel(t *testing.T) {
 
 	})
 
+	t.Run("Distinct Emails", func(t *testing.T) {
+		emails, err := database.DistinctAs[string](model.WithContext(context.Background()).Query(), "email")
+		require.NoError(t, err)
+		require.Contains(t, emails, "jane@doe.com")
+
+		ids, err := database.DistinctAs[uuid.UUID](model.WithContext(context.Background()).Query(database.WithFilter("email", "jane@doe.com")), "id")
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{uuid.MustParse("e527865d-c83e-4c21-a54b-275f057ecb56")}, ids)
+	})
+
+	t.Run("Iterate All Users", func(t *testing.T) {
+		var count int
+		for u, err := range model.WithContext(context.Background()).Query(database.WithBatchSize(1)).Iter() {
+			require.NoError(t, err)
+			require.NotEmpty(t, u)
+			count++
+		}
+		require.NotZero(t, count)
+	})
+
+	t.Run("Stop Iteration Early", func(t *testing.T) {
+		var count int
+		for _, err := range model.WithContext(context.Background()).Query().Iter() {
+			require.NoError(t, err)
+			count++
+			break
+		}
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("Iterate With Cancelled Context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := model.WithContext(ctx).Query().Each(func(u *UserModel) error {
+			return nil
+		})
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("Each User", func(t *testing.T) {
+		err := model.WithContext(context.Background()).Query(database.WithFilter("email", "jane@doe.com")).Each(func(u *UserModel) error {
+			require.Equal(t, "jane@doe.com", u.Email)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("Paginate Users", func(t *testing.T) {
+		for i := range 5 {
+			require.NoError(t, model.WithContext(context.Background()).Save(UserModel{
+				ID:    uuid.New(),
+				Email: fmt.Sprintf("page%d@doe.com", i),
+				Name:  "Page Doe",
+			}))
+		}
+
+		var emails []string
+		page, err := model.WithContext(context.Background()).Query(database.WithFilter("name", "Page Doe"), database.WithOrder("email", database.ASC)).Page(2, "")
+		require.NoError(t, err)
+		require.Empty(t, page.PrevToken)
+		for {
+			for _, u := range page.Items {
+				emails = append(emails, u.Email)
+			}
+			if page.NextToken == "" {
+				break
+			}
+			page, err = model.WithContext(context.Background()).Query(database.WithFilter("name", "Page Doe"), database.WithOrder("email", database.ASC)).Page(2, page.NextToken)
+			require.NoError(t, err)
+		}
+		require.Len(t, emails, 5)
+
+		prev, err := model.WithContext(context.Background()).Query(database.WithFilter("name", "Page Doe"), database.WithOrder("email", database.ASC)).Page(2, page.PrevToken)
+		require.NoError(t, err)
+		require.Len(t, prev.Items, 2)
+		require.Equal(t, emails[2], prev.Items[0].Email)
+
+		_, err = model.WithContext(context.Background()).Query(database.WithOrder("name", database.ASC)).Page(2, page.PrevToken)
+		require.ErrorIs(t, err, database.ErrInvalidPageToken)
+
+		paginated, err := model.WithContext(context.Background()).Query(database.WithFilter("name", "Page Doe"), database.WithOrder("email", database.ASC)).Paginate(2, 2)
+		require.NoError(t, err)
+		require.Len(t, paginated.Items, 2)
+		require.Equal(t, int64(5), paginated.Total)
+		require.Equal(t, int64(3), paginated.PageCount)
+		require.True(t, paginated.HasNext)
+
+		// once the documents before a token are gone, its page is the first
+		byEmail := []database.Params{database.WithFilter("name", "Page Doe"), database.WithOrder("email", database.ASC)}
+		first, err := model.WithContext(context.Background()).Query(byEmail...).Page(2, "")
+		require.NoError(t, err)
+		for _, u := range first.Items {
+			require.NoError(t, model.WithContext(context.Background()).Query(database.WithFilter("email", u.Email)).Delete())
+		}
+		second, err := model.WithContext(context.Background()).Query(byEmail...).Page(2, first.NextToken)
+		require.NoError(t, err)
+		require.Len(t, second.Items, 2)
+		require.Empty(t, second.PrevToken)
+		require.NotEmpty(t, second.NextToken)
+
+		require.NoError(t, model.WithContext(context.Background()).Query(database.WithFilter("name", "Page Doe")).DeleteMany())
+	})
+
 	t.Run("Delete User By ID", func(t *testing.T) {
 		err := model.WithContext(context.Background()).Query(database.WithFilter("id", uuid.MustParse("e527865d-c83e-4c21-a54b-275f057ecb56"))).Delete()
 		require.NoError(t, err)
 	})
 }
+
+func TestOrderingConformance(t *testing.T) {
+	mgd, err := New("mongodb://"+test_url, "test")
+	require.NoError(t, err)
+
+	model, err := RegisterModel(mgd, "ordering", databasetest.Record{})
+	require.NoError(t, err)
+
+	databasetest.Ordering(t, model)
+}
+
+func TestPageMissingValues(t *testing.T) {
+	mgd, err := New("mongodb://"+test_url, "test")
+	require.NoError(t, err)
+
+	type Player struct {
+		Name  string `db:"name"`
+		Score *int   `db:"score"`
+	}
+	model, err := RegisterModel(mgd, "paged_players", Player{})
+	require.NoError(t, err)
+	// documents written without the sort key at all sort with the nulls
+	_, err = mgd.Database().Collection("paged_players").InsertMany(context.Background(), []interface{}{
+		bson.D{{Key: "name", Value: "a"}, {Key: "score", Value: 2}},
+		bson.D{{Key: "name", Value: "b"}},
+		bson.D{{Key: "name", Value: "c"}, {Key: "score", Value: 1}},
+		bson.D{{Key: "name", Value: "d"}, {Key: "score", Value: nil}},
+		bson.D{{Key: "name", Value: "e"}},
+	})
+	require.NoError(t, err)
+
+	for _, dir := range []database.OrderType{database.ASC, database.DESC} {
+		order := []database.Params{database.WithOrder("score", dir), database.WithOrder("name", database.ASC)}
+		all, err := model.Query(order...).All()
+		require.NoError(t, err)
+		require.Len(t, all, 5)
+
+		var got []*Player
+		page, err := model.Query(order...).Page(2, "")
+		require.NoError(t, err)
+		for {
+			got = append(got, page.Items...)
+			if page.NextToken == "" {
+				break
+			}
+			page, err = model.Query(order...).Page(2, page.NextToken)
+			require.NoError(t, err)
+		}
+		require.Equal(t, all, got)
+	}
+}
+
+func TestReconcileIndexes(t *testing.T) {
+	mgd, err := New("mongodb://"+test_url, "test")
+	require.NoError(t, err)
+
+	type V1 struct {
+		Email string `db:"email,index"`
+		Name  string `db:"name,index"`
+	}
+	type V2 struct {
+		Email string `db:"email,index,unique"`
+		Name  string `db:"name"`
+	}
+
+	_, err = RegisterModel(mgd, "reconcile", V1{})
+	require.NoError(t, err)
+
+	// unique changed on email, so registering reports the rebuild instead of
+	// conflicting, and leaves it to an explicit run
+	var (
+		pendingColl string
+		pending     []IndexChange
+	)
+	_, err = RegisterModel(mgd, "reconcile", V2{}, WithPendingIndexes(func(coll string, changes []IndexChange) {
+		pendingColl, pending = coll, changes
+	}))
+	require.NoError(t, err)
+
+	specs, err := database.IndexesOf(V2{})
+	require.NoError(t, err)
+
+	changes, err := ReconcileIndexes(context.Background(), mgd, "reconcile", specs, IndexDryRun)
+	require.NoError(t, err)
+	require.Equal(t, []IndexChange{
+		{Action: IndexRebuild, Name: "email_1", Spec: specs[0], Reason: "unique changed"},
+		{Action: IndexDrop, Name: "name_1", Reason: "not declared"},
+	}, changes)
+	require.Equal(t, "reconcile", pendingColl)
+	require.Equal(t, changes, pending)
+
+	// without the option the same changes are logged
+	var buf bytes.Buffer
+	logger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	_, err = RegisterModel(mgd, "reconcile", V2{})
+	slog.SetDefault(logger)
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), "rebuild email_1 (unique changed)")
+	require.Contains(t, buf.String(), "drop name_1 (not declared)")
+
+	_, err = ReconcileIndexes(context.Background(), mgd, "reconcile", specs, IndexApply)
+	require.NoError(t, err)
+
+	changes, err = ReconcileIndexes(context.Background(), mgd, "reconcile", specs, IndexDryRun)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
+
+func TestRegisterModelWithValidation(t *testing.T) {
+	mgd, err := New("mongodb://"+test_url, "test")
+	require.NoError(t, err)
+
+	type Account struct {
+		ID    string  `db:"mongoid"`
+		Email string  `db:"email,required,min=3"`
+		Role  string  `db:"role,enum=admin|member"`
+		Plan  *string `db:"plan,enum=free|pro"`
+		Nick  string  `db:"nick,min=2"`
+		Age   int     `db:"age,min=18"`
+	}
+
+	model, err := RegisterModel(mgd, "accounts", Account{}, WithValidation(ValidationStrict, ValidationError))
+	require.NoError(t, err)
+
+	require.NoError(t, model.Save(Account{Email: "jon@doe.com", Role: "admin"}))
+	require.Error(t, model.Save(Account{Email: "jon@doe.com", Role: "owner"}))
+
+	// optional fields left empty are written with their zero value
+	require.NoError(t, model.Save(Account{Email: "jon@doe.com"}))
+	require.NoError(t, model.Save(Account{Email: "jon@doe.com", Plan: ptr("pro"), Nick: "jd", Age: 30}))
+	require.Error(t, model.Save(Account{Email: "jon@doe.com", Nick: "j"}))
+	require.Error(t, model.Save(Account{Email: "jon@doe.com", Age: 12}))
+	require.Error(t, model.Save(Account{Email: "jon@doe.com", Plan: ptr("gold")}))
+
+	// registering again runs collMod on the existing collection
+	_, err = RegisterModel(mgd, "accounts", Account{}, WithValidation(ValidationModerate, ValidationWarn))
+	require.NoError(t, err)
+	require.NoError(t, model.Save(Account{Email: "jon@doe.com", Role: "owner"}))
+}
+
+func TestInterceptors(t *testing.T) {
+	mgd, err := New("mongodb://"+test_url, "test")
+	require.NoError(t, err)
+
+	type Item struct {
+		Name string `db:"name"`
+	}
+	denied := errors.New("denied")
+	model, err := RegisterModel(mgd, "intercepted", Item{}, WithInterceptors(
+		func(ctx context.Context, op *database.OpInfo, next func(context.Context) error) error {
+			if op.Operation == database.OpDeleteMany && op.Query == "{}" {
+				return denied
+			}
+			return next(ctx)
+		}))
+	require.NoError(t, err)
+
+	var ops []database.OpInfo
+	mgd.Use(func(ctx context.Context, op *database.OpInfo, next func(context.Context) error) error {
+		err := next(ctx)
+		ops = append(ops, *op)
+		return err
+	})
+
+	require.NoError(t, model.Save(Item{Name: "a"}))
+	_, err = model.Query(database.WithFilter("name", "a"), database.WithLimit(5)).All()
+	require.NoError(t, err)
+	_, err = model.Query(database.WithFilter("name", "missing")).First()
+	require.Error(t, err)
+	require.ErrorIs(t, model.Query().DeleteMany(), denied)
+
+	require.Len(t, ops, 4)
+	require.Equal(t, database.OpInsert, ops[0].Operation)
+	require.Equal(t, "intercepted", ops[0].Collection)
+	require.Equal(t, database.OpFind, ops[1].Operation)
+	require.Equal(t, `{"name":"?"}`, ops[1].Query)
+	require.Equal(t, int64(5), ops[1].Limit)
+	require.Equal(t, int64(1), ops[1].Docs)
+	require.Equal(t, database.OpFindOne, ops[2].Operation)
+	require.Error(t, ops[2].Err)
+	// rejected before reaching the server
+	require.Equal(t, database.OpDeleteMany, ops[3].Operation)
+	require.Zero(t, ops[3].Duration)
+
+	// the consumer of an iteration isn't timed with the query
+	for _, err := range model.Query().Iter() {
+		require.NoError(t, err)
+		time.Sleep(200 * time.Millisecond)
+	}
+	require.Len(t, ops, 5)
+	require.Equal(t, int64(1), ops[4].Docs)
+	require.Less(t, ops[4].Duration, 200*time.Millisecond)
+}
+
+func TestSlowQueryLogExplain(t *testing.T) {
+	var buf bytes.Buffer
+	mgd, err := New("mongodb://"+test_url, "test", WithSlowQueryLog(SlowQueryLog{
+		Logger:  slog.New(slog.NewTextHandler(&buf, nil)),
+		Explain: true,
+	}))
+	require.NoError(t, err)
+
+	type Account struct {
+		Email string `db:"email,index"`
+	}
+	model, err := RegisterModel(mgd, "slow_accounts", Account{})
+	require.NoError(t, err)
+	require.NoError(t, model.Save(Account{Email: "a@example.com"}))
+	buf.Reset()
+
+	_, err = model.Query(database.WithFilter("email", "a@example.com")).First()
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), `filter="{\"email\":\"?\"}"`)
+	require.Contains(t, buf.String(), "IXSCAN(email_1)")
+	require.Contains(t, buf.String(), "docs_examined=1")
+	require.NotContains(t, buf.String(), "a@example.com")
+}
+
+func TestExplain(t *testing.T) {
+	mgd, err := New("mongodb://"+test_url, "test")
+	require.NoError(t, err)
+
+	type Account struct {
+		Email string `db:"email,index"`
+		Name  string `db:"name"`
+	}
+	model, err := RegisterModel(mgd, "explained_accounts", Account{})
+	require.NoError(t, err)
+	require.NoError(t, model.Save(Account{Email: "a@example.com", Name: "a"}, Account{Email: "b@example.com", Name: "b"}))
+
+	plan, err := model.Query(database.WithFilter("email", "a@example.com")).Explain(database.ExplainExecutionStats)
+	require.NoError(t, err)
+	require.True(t, plan.UsesIndexNamed("email_1"), plan.Summary)
+	require.Equal(t, int64(1), plan.DocsReturned)
+	require.Equal(t, int64(1), plan.DocsExamined)
+	require.NotEmpty(t, plan.Raw)
+
+	plan, err = model.Query(database.WithFilter("name", "a")).Explain(database.ExplainQueryPlanner)
+	require.NoError(t, err)
+	require.False(t, plan.UsesIndex())
+	require.True(t, plan.CollectionScan)
+	require.Zero(t, plan.DocsExamined)
+}
+
+func TestSession(t *testing.T) {
+	mgd, err := New("mongodb://"+test_url, "test")
+	require.NoError(t, err)
+
+	type Member struct {
+		ID    string `db:"mongoid"`
+		Name  string `db:"name"`
+		Email string `db:"email"`
+	}
+	model, err := RegisterModel(mgd, "session_members", Member{})
+	require.NoError(t, err)
+	require.NoError(t, model.Save(Member{Name: "Ann", Email: "ann@example.com"}))
+	saved, err := model.Query(database.WithFilter("name", "Ann")).First()
+	require.NoError(t, err)
+
+	s := database.NewSession(context.Background(), nil)
+	ann, err := database.Load(s, model, saved.ID)
+	require.NoError(t, err)
+	again, err := database.Load(s, model, saved.ID)
+	require.NoError(t, err)
+	require.Same(t, ann, again)
+
+	ann.Name = "Anna"
+	database.Add(s, model, Member{Name: "Bob", Email: "bob@example.com"})
+	require.NoError(t, s.Commit())
+
+	reloaded, err := model.Query(database.WithFilter("_id", saved.ID)).First()
+	require.NoError(t, err)
+	require.Equal(t, Member{ID: saved.ID, Name: "Anna", Email: "ann@example.com"}, *reloaded)
+	count, err := model.Query().Count()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	require.Error(t, model.Query(database.WithFilter("_id", saved.ID)).UpdateFields(*reloaded, "unknown"))
+
+	// the same id in another collection is another document, and loading
+	// through the session leaves the context of the models alone
+	archive, err := RegisterModel(mgd, "session_archive", Member{})
+	require.NoError(t, err)
+	require.NoError(t, archive.Save(Member{ID: saved.ID, Name: "Ann"}))
+
+	type key struct{}
+	s = database.NewSession(context.WithValue(context.Background(), key{}, "request"), nil)
+	ann, err = database.Load(s, model, saved.ID)
+	require.NoError(t, err)
+	archived, err := database.Load(s, archive, saved.ID)
+	require.NoError(t, err)
+	require.NotSame(t, ann, archived)
+	require.Equal(t, "Ann", archived.Name)
+	require.Nil(t, model.(*MongoModel[Member]).ctx.Value(key{}))
+}
+
+func TestUpdateOperators(t *testing.T) {
+	mgd, err := New("mongodb://"+test_url, "test")
+	require.NoError(t, err)
+
+	type Profile struct {
+		Name   string    `db:"name"`
+		Email  string    `db:"email"`
+		Visits int       `db:"visits"`
+		Tags   []string  `db:"tags"`
+		Seen   time.Time `db:"seen"`
+	}
+	model, err := RegisterModel(mgd, "update_profiles", Profile{})
+	require.NoError(t, err)
+	require.NoError(t, model.Save(Profile{Name: "Ann", Email: "ann@example.com", Visits: 1, Tags: []string{"a"}}))
+	byName := database.WithFilter("name", "Ann")
+
+	err = model.Query(byName).Set("email", "anna@example.com").Inc("visits", 2).
+		AddToSet("tags", "a", "b").CurrentDate("seen").Apply()
+	require.NoError(t, err)
+
+	got, err := model.Query(byName).First()
+	require.NoError(t, err)
+	require.Equal(t, "anna@example.com", got.Email)
+	require.Equal(t, 3, got.Visits)
+	require.Equal(t, []string{"a", "b"}, got.Tags)
+	require.WithinDuration(t, time.Now(), got.Seen, time.Minute)
+
+	// a PATCH touching only the name keeps the email
+	require.NoError(t, model.Query(byName).UpdateFields(Profile{Name: "Annie"}, "name"))
+	got, err = model.Query(database.WithFilter("name", "Annie")).First()
+	require.NoError(t, err)
+	require.Equal(t, "anna@example.com", got.Email)
+
+	require.Error(t, model.Query().Set("nickname", "A").ApplyMany())
+	require.ErrorIs(t, model.Query().ApplyMany(), errEmptyUpdate)
+	require.NoError(t, model.Query().Pull("tags", "a").ApplyMany())
+}
+
+func TestPreload(t *testing.T) {
+	mgd, err := New("mongodb://"+test_url, "test")
+	require.NoError(t, err)
+
+	type User struct {
+		ID   string `db:"mongoid"`
+		Name string `db:"name"`
+	}
+	type Comment struct {
+		ID     string `db:"mongoid"`
+		PostID string `db:"post_id,ref=preload_posts"`
+		Body   string `db:"body"`
+	}
+	type Tag struct {
+		ID   string `db:"mongoid"`
+		Name string `db:"name"`
+	}
+	type PostTag struct {
+		PostID string `db:"post_id"`
+		TagID  string `db:"tag_id"`
+	}
+	type Post struct {
+		ID       string     `db:"mongoid"`
+		Title    string     `db:"title"`
+		AuthorID string     `db:"author_id,ref=preload_users"`
+		Author   *User      `db:"-" rel:"belongs_to,local=author_id"`
+		Comments []*Comment `db:"-" rel:"has_many,ref=preload_comments,foreign=post_id"`
+		Tags     []Tag      `db:"-" rel:"many_to_many,ref=preload_tags,through=preload_post_tags,local=post_id,foreign=tag_id"`
+	}
+	id := func() string { return bson.NewObjectID().Hex() }
+
+	users, err := RegisterModel(mgd, "preload_users", User{})
+	require.NoError(t, err)
+	comments, err := RegisterModel(mgd, "preload_comments", Comment{})
+	require.NoError(t, err)
+	tags, err := RegisterModel(mgd, "preload_tags", Tag{})
+	require.NoError(t, err)
+	postTags, err := RegisterModel(mgd, "preload_post_tags", PostTag{})
+	require.NoError(t, err)
+	posts, err := RegisterModel(mgd, "preload_posts", Post{})
+	require.NoError(t, err)
+
+	ann, bob := User{ID: id(), Name: "Ann"}, User{ID: id(), Name: "Bob"}
+	golang, db := Tag{ID: id(), Name: "go"}, Tag{ID: id(), Name: "mongo"}
+	first := Post{ID: id(), Title: "first", AuthorID: ann.ID}
+	second := Post{ID: id(), Title: "second", AuthorID: bob.ID}
+	require.NoError(t, users.Save(ann, bob))
+	require.NoError(t, tags.Save(golang, db))
+	require.NoError(t, posts.Save(first, second))
+	require.NoError(t, comments.Save(
+		Comment{ID: id(), PostID: first.ID, Body: "a"},
+		Comment{ID: id(), PostID: first.ID, Body: "b"},
+	))
+	require.NoError(t, postTags.Save(
+		PostTag{PostID: first.ID, TagID: golang.ID},
+		PostTag{PostID: first.ID, TagID: db.ID},
+		PostTag{PostID: second.ID, TagID: db.ID},
+	))
+
+	var finds []string
+	mgd.Use(func(ctx context.Context, op *database.OpInfo, next func(context.Context) error) error {
+		if op.Operation == database.OpFind {
+			finds = append(finds, op.Collection)
+		}
+		return next(ctx)
+	})
+
+	got, err := posts.Query(database.WithOrder("title", database.ASC),
+		database.WithPreload("Author", "Comments", "Tags")).All()
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, "Ann", got[0].Author.Name)
+	require.Equal(t, "Bob", got[1].Author.Name)
+	require.Len(t, got[0].Comments, 2)
+	require.Empty(t, got[1].Comments)
+	require.Equal(t, []Tag{golang, db}, got[0].Tags)
+	require.Equal(t, []Tag{db}, got[1].Tags)
+	require.Equal(t, []string{"preload_posts", "preload_users", "preload_comments", "preload_post_tags", "preload_tags"}, finds,
+		"one query per relation whatever the number of posts")
+
+	post, err := posts.Query(database.WithFilter("_id", second.ID), database.WithPreload("Author")).First()
+	require.NoError(t, err)
+	require.Equal(t, bob, *post.Author)
+	require.Nil(t, post.Comments)
+
+	_, err = posts.Query(database.WithPreload("Editor")).All()
+	require.Error(t, err)
+	_, err = posts.Query(database.WithPreload("Author")).All()
+	require.NoError(t, err, "the failed query is reset")
+}
+
+func TestTenancy(t *testing.T) {
+	mgd, err := New("mongodb://"+test_url, "test")
+	require.NoError(t, err)
+
+	type Invoice struct {
+		Tenant string `db:"tenant_id,tenant,index"`
+		Number string `db:"number"`
+		Total  int    `db:"total"`
+	}
+	model, err := RegisterModel(mgd, "tenant_invoices", Invoice{})
+	require.NoError(t, err)
+	acme := database.WithTenant(context.Background(), "acme")
+	globex := database.WithTenant(context.Background(), "globex")
+
+	require.ErrorIs(t, model.Save(Invoice{Number: "1"}), database.ErrNoTenant)
+	require.NoError(t, model.WithContext(acme).Save(Invoice{Number: "1", Total: 10}, Invoice{Tenant: "globex", Number: "2"}))
+	require.NoError(t, model.WithContext(globex).Save(Invoice{Number: "1", Total: 99}))
+
+	_, err = model.WithContext(context.Background()).Query().All()
+	require.ErrorIs(t, err, database.ErrNoTenant)
+	_, err = model.Query().Count()
+	require.ErrorIs(t, err, database.ErrNoTenant)
+
+	got, err := model.WithContext(acme).Query(database.WithOrder("number", database.ASC)).All()
+	require.NoError(t, err)
+	require.Equal(t, []*Invoice{{Tenant: "acme", Number: "1", Total: 10}, {Tenant: "acme", Number: "2"}}, got,
+		"inserts are stamped with the tenant of the context")
+
+	// a tenant can neither update nor move the documents of another
+	require.NoError(t, model.WithContext(acme).Query(database.WithFilter("number", "1")).Update(Invoice{Number: "1", Total: 20}))
+	require.NoError(t, model.WithContext(acme).Query().Inc("total", 1).ApplyMany())
+	inv, err := model.WithContext(globex).Query(database.WithFilter("number", "1")).First()
+	require.NoError(t, err)
+	require.Equal(t, Invoice{Tenant: "globex", Number: "1", Total: 99}, *inv)
+	inv, err = model.WithContext(acme).Query(database.WithFilter("number", "1")).First()
+	require.NoError(t, err)
+	require.Equal(t, Invoice{Tenant: "acme", Number: "1", Total: 21}, *inv)
+
+	require.NoError(t, model.WithContext(globex).Query().DeleteMany())
+	count, err := model.WithContext(acme).Query().Count()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	// a tenant's stream sees its own deletes, matched on the document
+	// before them, and none of the writes of another tenant
+	ctx, cancel := context.WithTimeout(acme, 10*time.Second)
+	defer cancel()
+	events, err := model.Watch(ctx)
+	require.NoError(t, err)
+	require.NoError(t, model.WithContext(globex).Save(Invoice{Number: "2"}))
+	require.NoError(t, model.WithContext(globex).Query(database.WithFilter("number", "2")).Delete())
+	require.NoError(t, model.WithContext(acme).Query(database.WithFilter("number", "2")).Delete())
+	require.NoError(t, model.WithContext(globex).Save(Invoice{Number: "3"}))
+	require.NoError(t, model.WithContext(acme).Save(Invoice{Number: "3"}))
+	event := <-events
+	require.NoError(t, event.Err)
+	require.Equal(t, database.ChangeDelete, event.Operation)
+	event = <-events
+	require.NoError(t, event.Err)
+	require.Equal(t, database.ChangeInsert, event.Operation)
+	require.Equal(t, Invoice{Tenant: "acme", Number: "3"}, *event.Document)
+}
+
+func TestRouter(t *testing.T) {
+	router, err := NewRouter("mongodb://"+test_url, func(tenant string) string { return "tenant_" + tenant })
+	require.NoError(t, err)
+
+	type Note struct {
+		Text string `db:"text"`
+	}
+	notes := RegisterTenantModel(router, "notes", Note{})
+	acme := database.WithTenant(context.Background(), "acme")
+	globex := database.WithTenant(context.Background(), "globex")
+
+	_, err = notes.For(context.Background())
+	require.ErrorIs(t, err, database.ErrNoTenant)
+
+	model, err := notes.For(acme)
+	require.NoError(t, err)
+	require.NoError(t, model.Save(Note{Text: "hello"}))
+
+	again, err := notes.For(acme)
+	require.NoError(t, err)
+	require.NotSame(t, model, again)
+
+	model, err = notes.For(globex)
+	require.NoError(t, err)
+	count, err := model.Query().Count()
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	// concurrent first uses register the model of a tenant once
+	initech := database.WithTenant(context.Background(), "initech")
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = notes.For(initech)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	require.Len(t, notes.models, 3)
+
+	db, err := router.Database(acme)
+	require.NoError(t, err)
+	require.Equal(t, "tenant_acme", db.Database().Name())
+	require.Same(t, db, router.Tenant("acme"))
+}
+
+func TestScopes(t *testing.T) {
+	mgd, err := New("mongodb://"+test_url, "test")
+	require.NoError(t, err)
+
+	type Task struct {
+		Title    string `db:"title"`
+		Status   string `db:"status"`
+		Archived bool   `db:"archived"`
+	}
+	model, err := RegisterModel(mgd, "scoped_tasks", Task{})
+	require.NoError(t, err)
+	model.Scope("open", database.WithFilter("status", "open")).
+		DefaultScope(database.WithFilter("archived", false))
+	require.NoError(t, model.Save(
+		Task{Title: "a", Status: "open"},
+		Task{Title: "b", Status: "done"},
+		Task{Title: "c", Status: "open", Archived: true},
+	))
+
+	count, err := model.Query().Count()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	got, err := model.Query(database.UseScope("open"), database.WithLimit(10)).All()
+	require.NoError(t, err)
+	require.Equal(t, []*Task{{Title: "a", Status: "open"}}, got)
+
+	count, err = model.Query(database.UseScope("open"), database.Unscoped()).Count()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	_, err = model.Query(database.UseScope("closed")).Count()
+	require.Error(t, err)
+	count, err = model.Query().Count()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count, "the failed query is reset")
+}
+
+func TestEncryptedFields(t *testing.T) {
+	mgd, err := New("mongodb://"+test_url, "test")
+	require.NoError(t, err)
+
+	keys, err := database.NewStaticKeys("k1", map[string][]byte{"k1": bytes.Repeat([]byte{7}, 32)})
+	require.NoError(t, err)
+	database.SetKeyProvider(keys)
+	t.Cleanup(func() { database.SetKeyProvider(nil) })
+
+	type Patient struct {
+		Name  string `db:"name"`
+		SSN   string `db:"ssn,encrypted"`
+		Email string `db:"email,encrypted=deterministic,unique"`
+		Age   int    `db:"age,encrypted"`
+	}
+	model, err := RegisterModel(mgd, "encrypted_patients", Patient{},
+		WithValidation(ValidationStrict, ValidationError))
+	require.NoError(t, err)
+	ann := Patient{Name: "Ann", SSN: "123-45-6789", Email: "ann@example.com", Age: 42}
+	require.NoError(t, model.Save(ann))
+
+	var raw bson.M
+	require.NoError(t, mgd.Database().Collection("encrypted_patients").FindOne(context.Background(), bson.D{}).Decode(&raw))
+	require.Equal(t, "Ann", raw["name"])
+	require.True(t, database.IsEncrypted(raw["ssn"]))
+	require.NotContains(t, raw["ssn"], "6789")
+
+	got, err := model.Query(database.WithFilter("email", "ann@example.com")).First()
+	require.NoError(t, err)
+	require.Equal(t, ann, *got)
+
+	got, err = model.Query(database.WithFilter("email", bson.M{"$in": bson.A{"bob@example.com", "ann@example.com"}})).First()
+	require.NoError(t, err)
+	require.Equal(t, ann, *got)
+
+	_, err = model.Query(database.WithFilter("email", bson.M{"$gt": "a"})).First()
+	require.Error(t, err, "range operators compare ciphertexts")
+
+	_, err = model.Query(database.WithFilter("ssn", "123-45-6789")).First()
+	require.Error(t, err, "randomly encrypted fields can't be filtered on")
+
+	require.Error(t, model.Save(Patient{Name: "Bob", Email: "ann@example.com"}), "the unique index sees equal ciphertexts")
+
+	byEmail := database.WithFilter("email", "ann@example.com")
+	require.NoError(t, model.Query(byEmail).Set("ssn", "000-00-0000").Apply())
+	got, err = model.Query(byEmail).First()
+	require.NoError(t, err)
+	require.Equal(t, "000-00-0000", got.SSN)
+	require.Error(t, model.Query(byEmail).Inc("age", 1).Apply())
+}
+
+func TestWatch(t *testing.T) {
+	mgd, err := New("mongodb://"+test_url, "test")
+	require.NoError(t, err)
+
+	type Order struct {
+		ID     string `db:"mongoid"`
+		Status string `db:"status"`
+	}
+	model, err := RegisterModel(mgd, "watched_orders", Order{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	events, err := model.Watch(ctx, database.WithFilter("status", "paid"))
+	require.NoError(t, err)
+
+	require.NoError(t, model.Save(Order{Status: "open"}, Order{Status: "paid"}))
+	event := <-events
+	require.NoError(t, event.Err)
+	require.Equal(t, database.ChangeInsert, event.Operation)
+	require.Equal(t, "paid", event.Document.Status)
+	require.Equal(t, event.Document.ID, event.Key)
+
+	// resuming after the insert sees the following update only
+	require.NoError(t, model.Query(database.WithFilter("status", "open")).Set("status", "paid").Apply())
+	resumed, err := model.Watch(ctx, database.WithFilter("status", "paid"), database.WithResumeToken(event.ResumeToken))
+	require.NoError(t, err)
+	event = <-resumed
+	require.NoError(t, event.Err)
+	require.Equal(t, database.ChangeUpdate, event.Operation)
+	require.Equal(t, []string{"status"}, event.UpdatedFields)
+
+	// deletes are matched against the document before them
+	require.NoError(t, model.Save(Order{Status: "open"}))
+	require.NoError(t, model.Query(database.WithFilter("_id", event.Key)).Delete())
+	require.NoError(t, model.Query(database.WithFilter("status", "open")).Delete())
+	require.NoError(t, model.Save(Order{Status: "paid"}))
+	deleted := <-resumed
+	require.NoError(t, deleted.Err)
+	require.Equal(t, database.ChangeDelete, deleted.Operation)
+	require.Equal(t, event.Key, deleted.Key)
+	event = <-resumed
+	require.NoError(t, event.Err)
+	require.Equal(t, database.ChangeInsert, event.Operation, "the delete of another status is skipped")
+
+	_, err = model.Watch(ctx, database.WithOrder("status", database.ASC))
+	require.Error(t, err)
+
+	// default scopes apply to streams as they do to queries
+	model.DefaultScope(database.WithFilter("status", "shipped"))
+	shipped, err := model.Watch(ctx)
+	require.NoError(t, err)
+	require.NoError(t, model.Save(Order{Status: "paid"}, Order{Status: "shipped"}))
+	event = <-shipped
+	require.NoError(t, event.Err)
+	require.Equal(t, "shipped", event.Document.Status)
+
+	cancel()
+	for range events {
+	}
+}
@@ -0,0 +1,364 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/neghi-go/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// pageCursor is the payload carried by a page token. It records the sort it
+// was issued for so a token cannot be replayed against a different ordering.
+type pageCursor struct {
+	Keys     []string        `bson:"k"`
+	Dirs     []int32         `bson:"d"`
+	Values   []bson.RawValue `bson:"v"`
+	Backward bool            `bson:"b"`
+}
+
+// Page implements database.Query.
+func (m *MongoModel[T]) Page(size int64, token string) (database.Page[T], error) {
+	var res database.Page[T]
+	if _, err := m.scope(); err != nil {
+		return res, err
+	}
+	ctx, op, filter, order, collation := m.ctx, m.opInfo(database.OpFind), m.filter, keysetOrder(m.order), m.collation
+	nulls := maps.Clone(m.nulls)
+	preload, _ := m.preloader()
+	m.reset()
+
+	if size <= 0 {
+		return res, errors.New("page size must be greater than zero")
+	}
+
+	var cursor *pageCursor
+	if token != "" {
+		c, err := decodePageCursor(token, order)
+		if err != nil {
+			return res, err
+		}
+		cursor = c
+	}
+
+	base, sort := filter, order
+	if cursor != nil {
+		if cursor.Backward {
+			sort, nulls = reverseOrder(order), reverseNulls(nulls)
+		}
+		filter = bson.D{{Key: "$and", Value: bson.A{filter, keysetFilter(sort, nulls, cursor.Values)}}}
+	}
+
+	op.Filter, op.Sort, op.Limit, op.Query = filter, sort, size+1, querySummary(filter)
+
+	var raws []bson.Raw
+	// behind reports whether a document precedes the page in sort order
+	behind := false
+	err := m.intercept(ctx, op, func(ctx context.Context) error {
+		var err error
+		raws, err = m.findPage(ctx, filter, sort, nulls, size+1, collation)
+		if err != nil {
+			return err
+		}
+		op.Docs = int64(len(raws))
+		if cursor == nil || len(raws) == 0 {
+			return nil
+		}
+		// the documents the token was issued after may be gone, so look for
+		// one before the page rather than assuming it
+		before := bson.D{{Key: "$and", Value: bson.A{base,
+			keysetFilter(reverseOrder(sort), reverseNulls(nulls), sortValues(sort, raws[0]))}}}
+		prev, err := m.findPage(ctx, before, reverseOrder(sort), reverseNulls(nulls), 1, collation)
+		behind = len(prev) > 0
+		return err
+	})
+	if err != nil {
+		return res, err
+	}
+
+	hasMore := int64(len(raws)) > size
+	if hasMore {
+		raws = raws[:size]
+	}
+	backward := cursor != nil && cursor.Backward
+	if backward {
+		slices.Reverse(raws)
+	}
+
+	for _, raw := range raws {
+		var single bson.D
+		if err := bson.Unmarshal(raw, &single); err != nil {
+			return res, err
+		}
+		var singleRes T
+		if err := convertFromBson(&singleRes, single); err != nil {
+			return res, err
+		}
+		res.Items = append(res.Items, &singleRes)
+	}
+	if err := preload(ctx, res.Items); err != nil {
+		return res, err
+	}
+	if len(raws) == 0 {
+		return res, nil
+	}
+
+	// walking backwards, the documents ahead in sort order are the previous
+	// ones and those behind the next ones
+	next, prev := hasMore, behind
+	if backward {
+		next, prev = behind, hasMore
+	}
+	if next {
+		if res.NextToken, err = encodePageCursor(order, raws[len(raws)-1], false); err != nil {
+			return res, err
+		}
+	}
+	if prev {
+		if res.PrevToken, err = encodePageCursor(order, raws[0], true); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// findPage returns up to limit documents matching filter in sort order.
+func (m *MongoModel[T]) findPage(ctx context.Context, filter, sort bson.D, nulls map[string]database.NullsOrder, limit int64, collation *options.Collation) ([]bson.Raw, error) {
+	var result *mongo.Cursor
+	var err error
+	if len(nulls) == 0 {
+		result, err = m.client.Find(ctx, filter, options.Find().SetSort(sort).SetLimit(limit).
+			SetCollation(collation))
+	} else {
+		// like finder, explicit null placement needs an aggregation
+		pipeline := mongo.Pipeline{{{Key: "$match", Value: filter}}}
+		pipeline = append(pipeline, sortStages(sort, nulls)...)
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+		result, err = m.client.Aggregate(ctx, pipeline, options.Aggregate().SetCollation(collation))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close(ctx)
+
+	var raws []bson.Raw
+	for result.Next(ctx) {
+		raws = append(raws, slices.Clone(result.Current))
+	}
+	return raws, result.Err()
+}
+
+// Paginate implements database.Query.
+func (m *MongoModel[T]) Paginate(page, perPage int64) (database.Paginated[T], error) {
+	if _, err := m.scope(); err != nil {
+		return database.Paginated[T]{}, err
+	}
+	ctx, op, filter, collation := m.ctx, m.opInfo(database.OpAggregate), m.filter, m.collation
+	preload, _ := m.preloader()
+	items := bson.A{}
+	for _, stage := range sortStages(m.order, m.nulls) {
+		items = append(items, stage)
+	}
+	m.reset()
+
+	if page < 1 || perPage < 1 {
+		return database.Paginated[T]{}, errors.New("page and page size must be greater than zero")
+	}
+
+	items = append(items,
+		bson.D{{Key: "$skip", Value: (page - 1) * perPage}},
+		bson.D{{Key: "$limit", Value: perPage}},
+	)
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$facet", Value: bson.D{
+			{Key: "items", Value: items},
+			{Key: "total", Value: bson.A{bson.D{{Key: "$count", Value: "count"}}}},
+		}}},
+	}
+
+	op.Limit, op.Offset = perPage, (page-1)*perPage
+
+	var facet struct {
+		Items []bson.D `bson:"items"`
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+	}
+	err := m.intercept(ctx, op, func(ctx context.Context) error {
+		result, err := m.client.Aggregate(ctx, pipeline, options.Aggregate().SetCollation(collation))
+		if err != nil {
+			return err
+		}
+		defer result.Close(ctx)
+
+		if result.Next(ctx) {
+			if err := result.Decode(&facet); err != nil {
+				return err
+			}
+		}
+		op.Docs = int64(len(facet.Items))
+		return result.Err()
+	})
+	if err != nil {
+		return database.Paginated[T]{}, err
+	}
+
+	var res []*T
+	for _, single := range facet.Items {
+		var singleRes T
+		if err := convertFromBson(&singleRes, single); err != nil {
+			return database.Paginated[T]{}, err
+		}
+		res = append(res, &singleRes)
+	}
+	if err := preload(ctx, res); err != nil {
+		return database.Paginated[T]{}, err
+	}
+	var total int64
+	if len(facet.Total) > 0 {
+		total = facet.Total[0].Count
+	}
+	return database.NewPaginated(res, total, page, perPage), nil
+}
+
+// keysetOrder returns the query order with _id appended as a tiebreaker so
+// every row has a unique position.
+func keysetOrder(order bson.D) bson.D {
+	res := slices.Clone(order)
+	for _, e := range res {
+		if e.Key == "_id" {
+			return res
+		}
+	}
+	return append(res, bson.E{Key: "_id", Value: 1})
+}
+
+func reverseOrder(order bson.D) bson.D {
+	var res bson.D
+	for _, e := range order {
+		res = append(res, bson.E{Key: e.Key, Value: -sortDirection(e.Value)})
+	}
+	return res
+}
+
+func sortDirection(v interface{}) int {
+	switch v := v.(type) {
+	case int:
+		return v
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	default:
+		return 1
+	}
+}
+
+// reverseNulls swaps the explicit null placements of an order being walked
+// backwards. Native placement needs no change, as nulls sort lowest both ways.
+func reverseNulls(nulls map[string]database.NullsOrder) map[string]database.NullsOrder {
+	res := map[string]database.NullsOrder{}
+	for k, n := range nulls {
+		switch n {
+		case database.NullsFirst:
+			res[k] = database.NullsLast
+		case database.NullsLast:
+			res[k] = database.NullsFirst
+		}
+	}
+	return res
+}
+
+// keysetFilter matches the documents strictly after values in sort order:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...
+// Null or missing values compare equal to each other and are placed as nulls
+// asks, by default lowest like the server sorts them. Comparison operators
+// never match them, so they get branches of their own.
+func keysetFilter(sort bson.D, nulls map[string]database.NullsOrder, values []bson.RawValue) bson.D {
+	var or bson.A
+	for i, e := range sort {
+		cond := bson.D{}
+		for j := 0; j < i; j++ {
+			if values[j].Type == bson.TypeNull {
+				cond = append(cond, bson.E{Key: sort[j].Key, Value: nil})
+			} else {
+				cond = append(cond, bson.E{Key: sort[j].Key, Value: values[j]})
+			}
+		}
+		first := nulls[e.Key] == database.NullsFirst ||
+			nulls[e.Key] == database.NullsDefault && sortDirection(e.Value) > 0
+		switch {
+		case values[i].Type == bson.TypeNull && !first:
+			// nothing sorts after trailing nulls
+			continue
+		case values[i].Type == bson.TypeNull:
+			cond = append(cond, bson.E{Key: e.Key, Value: bson.D{{Key: "$ne", Value: nil}}})
+		default:
+			op := "$gt"
+			if sortDirection(e.Value) < 0 {
+				op = "$lt"
+			}
+			after := bson.D{{Key: e.Key, Value: bson.D{{Key: op, Value: values[i]}}}}
+			if first {
+				cond = append(cond, after...)
+			} else {
+				cond = append(cond, bson.E{Key: "$or", Value: bson.A{after, bson.D{{Key: e.Key, Value: nil}}}})
+			}
+		}
+		or = append(or, cond)
+	}
+	return bson.D{{Key: "$or", Value: or}}
+}
+
+// sortValues returns the values of the sort keys in raw, null for missing
+// ones.
+func sortValues(sort bson.D, raw bson.Raw) []bson.RawValue {
+	var res []bson.RawValue
+	for _, e := range sort {
+		val, err := raw.LookupErr(strings.Split(e.Key, ".")...)
+		if err != nil {
+			val = bson.RawValue{Type: bson.TypeNull}
+		}
+		res = append(res, val)
+	}
+	return res
+}
+
+func encodePageCursor(order bson.D, raw bson.Raw, backward bool) (string, error) {
+	cursor := pageCursor{Backward: backward, Values: sortValues(order, raw)}
+	for _, e := range order {
+		cursor.Keys = append(cursor.Keys, e.Key)
+		cursor.Dirs = append(cursor.Dirs, int32(sortDirection(e.Value)))
+	}
+	payload, err := bson.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return database.EncodePageToken(payload), nil
+}
+
+func decodePageCursor(token string, order bson.D) (*pageCursor, error) {
+	payload, err := database.DecodePageToken(token)
+	if err != nil {
+		return nil, err
+	}
+	var cursor pageCursor
+	if err := bson.Unmarshal(payload, &cursor); err != nil {
+		return nil, database.ErrInvalidPageToken
+	}
+	if len(cursor.Keys) != len(order) || len(cursor.Dirs) != len(order) || len(cursor.Values) != len(order) {
+		return nil, database.ErrInvalidPageToken
+	}
+	for i, e := range order {
+		if cursor.Keys[i] != e.Key || int(cursor.Dirs[i]) != sortDirection(e.Value) {
+			return nil, database.ErrInvalidPageToken
+		}
+	}
+	return &cursor, nil
+}
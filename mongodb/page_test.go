@@ -0,0 +1,95 @@
+package mongodb
+
+import (
+	"testing"
+
+	"github.com/neghi-go/database"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func rawValue(t *testing.T, v interface{}) bson.RawValue {
+	typ, data, err := bson.MarshalValue(v)
+	require.NoError(t, err)
+	return bson.RawValue{Type: typ, Value: data}
+}
+
+func Test_keysetFilter(t *testing.T) {
+	id := rawValue(t, 7)
+	null := bson.RawValue{Type: bson.TypeNull}
+	tests := []struct {
+		name   string
+		sort   bson.D
+		nulls  map[string]database.NullsOrder
+		values []bson.RawValue
+		want   bson.D
+	}{
+		{
+			name:   "Test Ascending",
+			sort:   bson.D{{Key: "score", Value: 1}, {Key: "_id", Value: 1}},
+			values: []bson.RawValue{rawValue(t, 3), id},
+			want: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "score", Value: bson.D{{Key: "$gt", Value: rawValue(t, 3)}}}},
+				bson.D{{Key: "score", Value: rawValue(t, 3)}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: id}}}},
+			}}},
+		},
+		{
+			name:   "Test Ascending From Null",
+			sort:   bson.D{{Key: "score", Value: 1}, {Key: "_id", Value: 1}},
+			values: []bson.RawValue{null, id},
+			want: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "score", Value: bson.D{{Key: "$ne", Value: nil}}}},
+				bson.D{{Key: "score", Value: nil}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: id}}}},
+			}}},
+		},
+		{
+			name:   "Test Descending Keeps Trailing Nulls",
+			sort:   bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}},
+			values: []bson.RawValue{rawValue(t, 3), id},
+			want: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "$or", Value: bson.A{
+					bson.D{{Key: "score", Value: bson.D{{Key: "$lt", Value: rawValue(t, 3)}}}},
+					bson.D{{Key: "score", Value: nil}},
+				}}},
+				bson.D{{Key: "score", Value: rawValue(t, 3)}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: id}}}},
+			}}},
+		},
+		{
+			name:   "Test Descending From Null",
+			sort:   bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}},
+			values: []bson.RawValue{null, id},
+			want: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "score", Value: nil}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: id}}}},
+			}}},
+		},
+		{
+			name:   "Test Nulls Last",
+			sort:   bson.D{{Key: "score", Value: 1}, {Key: "_id", Value: 1}},
+			nulls:  map[string]database.NullsOrder{"score": database.NullsLast},
+			values: []bson.RawValue{null, id},
+			want: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "score", Value: nil}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: id}}}},
+			}}},
+		},
+		{
+			name:   "Test Nulls First",
+			sort:   bson.D{{Key: "score", Value: -1}, {Key: "_id", Value: 1}},
+			nulls:  map[string]database.NullsOrder{"score": database.NullsFirst},
+			values: []bson.RawValue{rawValue(t, 3), id},
+			want: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "score", Value: bson.D{{Key: "$lt", Value: rawValue(t, 3)}}}},
+				bson.D{{Key: "score", Value: rawValue(t, 3)}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: id}}}},
+			}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, keysetFilter(tt.sort, tt.nulls, tt.values))
+		})
+	}
+}
+
+func Test_reverseNulls(t *testing.T) {
+	require.Equal(t, map[string]database.NullsOrder{"a": database.NullsLast, "b": database.NullsFirst},
+		reverseNulls(map[string]database.NullsOrder{"a": database.NullsFirst, "b": database.NullsLast}))
+}
@@ -0,0 +1,230 @@
+package mongodb
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/neghi-go/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// defaultPreloadBatch is the number of documents Iter holds back to preload
+// their relations together when the query sets no batch size.
+const defaultPreloadBatch = 100
+
+// preloader captures the relations requested by the current query and
+// returns a function filling them on a batch of documents, along with the
+// number of documents worth batching.
+func (m *MongoModel[T]) preloader() (func(ctx context.Context, docs []*T) error, int) {
+	relations := m.preload
+	if len(relations) == 0 {
+		return func(context.Context, []*T) error { return nil }, 1
+	}
+	size := int(m.batch)
+	if size <= 0 {
+		size = defaultPreloadBatch
+	}
+	return func(ctx context.Context, docs []*T) error {
+		if len(docs) == 0 {
+			return nil
+		}
+		for _, r := range relations {
+			if err := m.preloadRelation(ctx, r, docs); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, size
+}
+
+// preloadRelation fills one relation on docs with a single $in query on the
+// related collection, preceded by one on the join collection for many to
+// many relations.
+func (m *MongoModel[T]) preloadRelation(ctx context.Context, r database.Relation, docs []*T) error {
+	localKey := r.LocalKey
+	if r.Kind == database.ManyToMany {
+		localKey = "_id"
+	}
+	locals := make([][]any, len(docs))
+	var ids []any
+	for i, doc := range docs {
+		enc, err := database.EncodeModel(*doc)
+		if err != nil {
+			return err
+		}
+		for _, p := range enc {
+			if p.Key == localKey {
+				locals[i] = relationKeys(p.Value)
+			}
+		}
+		ids = append(ids, locals[i]...)
+	}
+
+	// many to many relations go through the join collection, mapping the id
+	// of each document onto the ids of its related documents
+	matchKey := r.ForeignKey
+	if r.Kind == database.ManyToMany {
+		joins, err := m.findRelated(ctx, r.Through, r.LocalKey, ids, nil)
+		if err != nil {
+			return err
+		}
+		pairs := map[any][]any{}
+		ids = nil
+		for _, join := range joins {
+			for _, local := range relationKeys(lookupValue(join, r.LocalKey)) {
+				foreign := relationKeys(lookupValue(join, r.ForeignKey))
+				pairs[local] = append(pairs[local], foreign...)
+				ids = append(ids, foreign...)
+			}
+		}
+		for i, local := range locals {
+			var res []any
+			for _, id := range local {
+				res = append(res, pairs[id]...)
+			}
+			locals[i] = res
+		}
+		matchKey = "_id"
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	scope, err := relatedScope(ctx, r.Type)
+	if err != nil {
+		return err
+	}
+	found, err := m.findRelated(ctx, r.Collection, matchKey, ids, scope)
+	if err != nil {
+		return err
+	}
+	byKey := map[any][]reflect.Value{}
+	for _, d := range found {
+		related := reflect.New(r.Type)
+		if err := convertFromBson(related.Interface(), d); err != nil {
+			return err
+		}
+		for _, key := range relationKeys(lookupValue(d, matchKey)) {
+			byKey[key] = append(byKey[key], related)
+		}
+	}
+	for i, doc := range docs {
+		var related []reflect.Value
+		for _, id := range locals[i] {
+			related = append(related, byKey[id]...)
+		}
+		database.SetRelation(doc, r, related)
+	}
+	return nil
+}
+
+// relatedScope returns the tenant filter of a related model, empty when it
+// has no tenant field.
+func relatedScope(ctx context.Context, t reflect.Type) (bson.D, error) {
+	fields, err := database.FieldsOf(reflect.New(t).Interface())
+	if err != nil {
+		return nil, err
+	}
+	tenant, ok, err := database.TenantField(fields)
+	if err != nil || !ok {
+		return nil, err
+	}
+	id, ok := database.TenantFrom(ctx)
+	if !ok {
+		return nil, database.ErrNoTenant
+	}
+	return bson.D{{Key: tenant.Key, Value: id}}, nil
+}
+
+// findRelated returns the documents of coll matching scope whose key matches
+// one of ids, ordered by id. It runs through the interceptors of the database
+// rather than those of the model, as it reads another collection.
+func (m *MongoModel[T]) findRelated(ctx context.Context, coll, key string, ids []any, scope bson.D) ([]bson.D, error) {
+	values := make(bson.A, 0, len(ids))
+	seen := map[any]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			values = append(values, filterValue(key, id))
+		}
+	}
+	filter := append(scope, bson.E{Key: key, Value: bson.D{{Key: "$in", Value: values}}})
+	sort := bson.D{{Key: "_id", Value: 1}}
+	client := m.client.Database().Collection(coll)
+	op := &database.OpInfo{
+		System:     "mongodb",
+		Database:   client.Database().Name(),
+		Collection: coll,
+		Operation:  database.OpFind,
+		Filter:     filter,
+		Sort:       sort,
+		Query:      querySummary(filter),
+	}
+
+	var chain []database.Interceptor
+	if m.conn != nil {
+		chain = m.conn.chain()
+	}
+	var res []bson.D
+	err := database.Intercept(ctx, chain, op, func(ctx context.Context) error {
+		result, err := client.Find(ctx, filter, options.Find().SetSort(sort))
+		if err != nil {
+			return err
+		}
+		if err := result.All(ctx, &res); err != nil {
+			return err
+		}
+		op.Docs = int64(len(res))
+		return nil
+	})
+	return res, err
+}
+
+// lookupValue returns the value of key in doc, nil when it is missing.
+func lookupValue(doc bson.D, key string) any {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value
+		}
+	}
+	return nil
+}
+
+// relationKeys normalises the ids held by a field into comparable values, so
+// an id read from a model matches the same id read from the database. Lists
+// of ids yield one key per element and empty ids are left out.
+func relationKeys(value any) []any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case bson.A:
+		return relationKeys([]any(v))
+	case []any:
+		var res []any
+		for _, e := range v {
+			res = append(res, relationKeys(e)...)
+		}
+		return res
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() != reflect.Uint8 {
+			var res []any
+			for i := 0; i < rv.Len(); i++ {
+				res = append(res, relationKeys(rv.Index(i).Interface())...)
+			}
+			return res
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []any{rv.Int()}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return []any{int64(rv.Uint())}
+	}
+	value, err := convertBsonValue(value)
+	if err != nil || value == nil || value == "" || !reflect.TypeOf(value).Comparable() {
+		return nil
+	}
+	return []any{value}
+}
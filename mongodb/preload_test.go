@@ -0,0 +1,34 @@
+package mongodb
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func Test_relationKeys(t *testing.T) {
+	id := bson.NewObjectID()
+	u := uuid.New()
+	tests := []struct {
+		name  string
+		value any
+		want  []any
+	}{
+		{name: "Test ObjectID", value: id, want: []any{id.Hex()}},
+		{name: "Test Hex String", value: id.Hex(), want: []any{id.Hex()}},
+		{name: "Test Int Widths", value: int32(7), want: []any{int64(7)}},
+		{name: "Test UUID", value: u, want: []any{u}},
+		{name: "Test List", value: bson.A{id, "", "b"}, want: []any{id.Hex(), "b"}},
+		{name: "Test String Slice", value: []string{"a", "b"}, want: []any{"a", "b"}},
+		{name: "Test Empty", value: "", want: nil},
+		{name: "Test Missing", value: nil, want: nil},
+		{name: "Test Not Comparable", value: map[string]any{"a": 1}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, relationKeys(tt.value))
+		})
+	}
+}
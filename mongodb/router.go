@@ -0,0 +1,164 @@
+package mongodb
+
+import (
+	"context"
+	"sync"
+
+	"github.com/neghi-go/database"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"golang.org/x/sync/singleflight"
+)
+
+// Router gives every tenant its own database, for deployments isolating
+// tenants by database rather than with a tenant field. All the databases
+// share the connection pool of a single client.
+type Router struct {
+	client *mongo.Client
+	cfg    clientConfig
+	name   func(tenant string) string
+
+	mu           sync.Mutex
+	dbs          map[string]*mongoDatabase
+	interceptors []database.Interceptor
+}
+
+// NewRouter connects to url like New, name mapping a tenant onto the name of
+// its database, e.g. func(t string) string { return "app_" + t }. The tenant
+// ids must only produce valid database names.
+func NewRouter(url string, name func(tenant string) string, opts ...Option) (*Router, error) {
+	client, cfg, err := connect(url, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Router{
+		client: client,
+		cfg:    cfg,
+		name:   name,
+		dbs:    map[string]*mongoDatabase{},
+	}, nil
+}
+
+// Tenant returns the database of tenant.
+func (r *Router) Tenant(tenant string) *mongoDatabase {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if db, ok := r.dbs[tenant]; ok {
+		return db
+	}
+	db := newDatabase(r.client.Database(r.name(tenant)), r.cfg)
+	db.Use(r.interceptors...)
+	r.dbs[tenant] = db
+	return db
+}
+
+// Database returns the database of the tenant set on ctx with
+// database.WithTenant, and database.ErrNoTenant when there is none.
+func (r *Router) Database(ctx context.Context) (*mongoDatabase, error) {
+	tenant, ok := database.TenantFrom(ctx)
+	if !ok {
+		return nil, database.ErrNoTenant
+	}
+	return r.Tenant(tenant), nil
+}
+
+// Use adds interceptors to the database of every tenant, including those
+// already handed out.
+func (r *Router) Use(interceptors ...database.Interceptor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.interceptors = append(r.interceptors, interceptors...)
+	for _, db := range r.dbs {
+		db.Use(interceptors...)
+	}
+}
+
+func (r *Router) Disconnect(ctx context.Context) error {
+	return r.client.Disconnect(ctx)
+}
+
+// TenantModel registers a model on the database of each tenant the first
+// time the tenant uses it. The scopes registered on it are shared by the
+// models of every tenant.
+type TenantModel[T any] struct {
+	router *Router
+	coll   string
+	model  T
+	opts   []RegisterOption
+	scopes *database.Scopes
+
+	// registering runs once per tenant at a time, out of mu so tenants
+	// don't wait on each other, and a failed registration is retried by the
+	// next call
+	group  singleflight.Group
+	mu     sync.Mutex
+	models map[string]*MongoModel[T]
+}
+
+// RegisterTenantModel declares the model of coll on every tenant database.
+// Like RegisterModel, registration creates the indexes and validator of the
+// model, here on the first use by each tenant.
+func RegisterTenantModel[T any](r *Router, coll string, model T, opts ...RegisterOption) *TenantModel[T] {
+	return &TenantModel[T]{
+		router: r,
+		coll:   coll,
+		model:  model,
+		opts:   opts,
+		scopes: &database.Scopes{},
+		models: map[string]*MongoModel[T]{},
+	}
+}
+
+// Scope registers a named scope on the model of every tenant.
+func (t *TenantModel[T]) Scope(name string, params ...database.Params) *TenantModel[T] {
+	t.scopes.Add(name, params...)
+	return t
+}
+
+// DefaultScope registers a default scope on the model of every tenant.
+func (t *TenantModel[T]) DefaultScope(params ...database.Params) *TenantModel[T] {
+	t.scopes.AddDefault(params...)
+	return t
+}
+
+// For returns the model of the tenant set on ctx, bound to ctx. Each call
+// returns its own copy of the tenant model, so concurrent requests never
+// share query state.
+func (t *TenantModel[T]) For(ctx context.Context) (database.Model[T], error) {
+	tenant, ok := database.TenantFrom(ctx)
+	if !ok {
+		return nil, database.ErrNoTenant
+	}
+
+	t.mu.Lock()
+	model, ok := t.models[tenant]
+	t.mu.Unlock()
+	if !ok {
+		res, err, _ := t.group.Do(tenant, func() (any, error) {
+			t.mu.Lock()
+			model, ok := t.models[tenant]
+			t.mu.Unlock()
+			if ok {
+				return model, nil
+			}
+			model, err := registerModel(t.router.Tenant(tenant), t.coll, t.model, t.opts...)
+			if err != nil {
+				return nil, err
+			}
+			model.scopes = t.scopes
+
+			t.mu.Lock()
+			t.models[tenant] = model
+			t.mu.Unlock()
+			return model, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		model = res.(*MongoModel[T])
+	}
+	handle := *model
+	handle.ctx = ctx
+	return &handle, nil
+}
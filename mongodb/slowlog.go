@@ -0,0 +1,98 @@
+package mongodb
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/neghi-go/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// explainTimeout bounds the explain run for a slow query, which happens after
+// the query itself, possibly once its context is done.
+const explainTimeout = 5 * time.Second
+
+// SlowQueryLog configures the slow query log.
+type SlowQueryLog struct {
+	// Threshold is the duration from which an operation is logged.
+	Threshold time.Duration
+	// Logger receives the records, slog.Default() when nil.
+	Logger *slog.Logger
+	// Explain runs slow reads (find, count, distinct and aggregate) again
+	// through explain with execution stats and attaches the winning plan with
+	// the keys and documents examined. Without it a record only carries the
+	// documents the operation returned or matched, as the driver reports
+	// nothing on what the server examined. This repeats the work of every
+	// slow read, so it is best left to staging environments.
+	Explain bool
+}
+
+// WithSlowQueryLog logs a warning for every operation taking cfg.Threshold or
+// longer, with its filter values redacted.
+func WithSlowQueryLog(cfg SlowQueryLog) Option {
+	return func(c *clientConfig) {
+		c.slow = &cfg
+	}
+}
+
+func (m *mongoDatabase) slowQueries(cfg SlowQueryLog) database.Interceptor {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(ctx context.Context, op *database.OpInfo, next func(context.Context) error) error {
+		err := next(ctx)
+		if op.Duration < cfg.Threshold {
+			return err
+		}
+
+		attrs := []slog.Attr{
+			slog.String("collection", op.Collection),
+			slog.String("operation", op.Operation),
+			slog.String("filter", op.Query),
+			slog.String("sort", sortSummary(op.Sort)),
+			slog.Int64("limit", op.Limit),
+			slog.Int64("offset", op.Offset),
+			slog.Duration("duration", op.Duration),
+			slog.Int64("docs", op.Docs),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		if cfg.Explain && explainable(op) {
+			attrs = append(attrs, m.explainAttrs(ctx, op)...)
+		}
+		logger.LogAttrs(ctx, slog.LevelWarn, "slow query", attrs...)
+		return err
+	}
+}
+
+func (m *mongoDatabase) explainAttrs(ctx context.Context, op *database.OpInfo) []slog.Attr {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), explainTimeout)
+	defer cancel()
+
+	plan, err := m.explain(ctx, findCommand(op), database.ExplainExecutionStats)
+	if err != nil {
+		return []slog.Attr{slog.String("explain_error", err.Error())}
+	}
+	return []slog.Attr{
+		slog.Int64("docs_examined", plan.DocsExamined),
+		slog.Int64("keys_examined", plan.KeysExamined),
+		slog.String("plan", plan.Summary),
+	}
+}
+
+// sortSummary renders a sort as extended JSON. Sorts only carry field names
+// and directions, so nothing is redacted.
+func sortSummary(sort interface{}) string {
+	d, ok := sort.(bson.D)
+	if !ok || len(d) == 0 {
+		return "{}"
+	}
+	b, err := bson.MarshalExtJSON(d, false, false)
+	if err != nil {
+		return "{?}"
+	}
+	return string(b)
+}
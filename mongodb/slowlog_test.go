@@ -0,0 +1,230 @@
+package mongodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/neghi-go/database"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestSlowQueries(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name     string
+		op       database.OpInfo
+		sleep    time.Duration
+		err      error
+		wantLogs bool
+		want     map[string]interface{}
+	}{
+		{
+			name:  "Test Fast Query",
+			op:    database.OpInfo{Collection: "users", Operation: database.OpFind},
+			sleep: 0,
+		},
+		{
+			name: "Test Slow Query",
+			op: database.OpInfo{
+				Collection: "users",
+				Operation:  database.OpFind,
+				Query:      `{"email":"?"}`,
+				Sort:       bson.D{{Key: "created_at", Value: -1}},
+				Limit:      10,
+				Offset:     20,
+			},
+			sleep:    20 * time.Millisecond,
+			wantLogs: true,
+			want: map[string]interface{}{
+				"msg":        "slow query",
+				"level":      "WARN",
+				"collection": "users",
+				"operation":  "find",
+				"filter":     `{"email":"?"}`,
+				"sort":       `{"created_at":-1}`,
+				"limit":      float64(10),
+				"offset":     float64(20),
+				"docs":       float64(3),
+			},
+		},
+		{
+			name:     "Test Slow Failed Query",
+			op:       database.OpInfo{Collection: "users", Operation: database.OpCount, Query: "{}"},
+			sleep:    20 * time.Millisecond,
+			err:      errFailed,
+			wantLogs: true,
+			want: map[string]interface{}{
+				"msg":        "slow query",
+				"level":      "WARN",
+				"collection": "users",
+				"operation":  "count",
+				"filter":     "{}",
+				"sort":       "{}",
+				"limit":      float64(0),
+				"offset":     float64(0),
+				"docs":       float64(3),
+				"error":      "failed",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, nil))
+			conn := &mongoDatabase{}
+			chain := []database.Interceptor{conn.slowQueries(SlowQueryLog{Threshold: 10 * time.Millisecond, Logger: logger})}
+
+			err := database.Intercept(context.Background(), chain, &tt.op, func(context.Context) error {
+				time.Sleep(tt.sleep)
+				tt.op.Docs = 3
+				return tt.err
+			})
+			require.ErrorIs(t, err, tt.err)
+			if !tt.wantLogs {
+				require.Empty(t, buf.String())
+				return
+			}
+
+			var record map[string]interface{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+			require.NotContains(t, record, "plan")
+			require.GreaterOrEqual(t, record["duration"], float64(tt.sleep))
+			delete(record, "time")
+			delete(record, "duration")
+			require.Equal(t, tt.want, record)
+		})
+	}
+}
+
+func Test_parseExplain(t *testing.T) {
+	ixscan := func(name string) bson.D {
+		return bson.D{{Key: "stage", Value: "IXSCAN"}, {Key: "indexName", Value: name}}
+	}
+
+	tests := []struct {
+		name  string
+		reply bson.D
+		want  database.Plan
+	}{
+		{
+			name: "Test Classic Plan",
+			reply: bson.D{
+				{Key: "queryPlanner", Value: bson.D{{Key: "winningPlan", Value: bson.D{
+					{Key: "stage", Value: "LIMIT"},
+					{Key: "inputStage", Value: bson.D{{Key: "stage", Value: "FETCH"}, {Key: "inputStage", Value: ixscan("email_1")}}},
+				}}}},
+				{Key: "executionStats", Value: bson.D{
+					{Key: "nReturned", Value: int32(1)},
+					{Key: "totalDocsExamined", Value: int32(4)},
+					{Key: "totalKeysExamined", Value: int64(5)},
+					{Key: "executionTimeMillis", Value: int32(2)},
+				}},
+			},
+			want: database.Plan{
+				Stages:        []string{"LIMIT", "FETCH", "IXSCAN"},
+				Indexes:       []string{"email_1"},
+				Summary:       "LIMIT > FETCH > IXSCAN(email_1)",
+				DocsReturned:  1,
+				DocsExamined:  4,
+				KeysExamined:  5,
+				ExecutionTime: 2 * time.Millisecond,
+			},
+		},
+		{
+			name: "Test Slot Based Plan",
+			reply: bson.D{
+				{Key: "queryPlanner", Value: bson.D{{Key: "winningPlan", Value: bson.D{
+					{Key: "queryPlan", Value: bson.D{{Key: "stage", Value: "COLLSCAN"}}},
+					{Key: "slotBasedPlan", Value: bson.D{}},
+				}}}},
+			},
+			want: database.Plan{Stages: []string{"COLLSCAN"}, CollectionScan: true, Summary: "COLLSCAN"},
+		},
+		{
+			name: "Test Branching Plan",
+			reply: bson.D{
+				{Key: "queryPlanner", Value: bson.D{{Key: "winningPlan", Value: bson.D{
+					{Key: "stage", Value: "FETCH"},
+					{Key: "inputStage", Value: bson.D{
+						{Key: "stage", Value: "OR"},
+						{Key: "inputStages", Value: bson.A{ixscan("a_1"), ixscan("b_1")}},
+					}},
+				}}}},
+			},
+			want: database.Plan{
+				Stages:  []string{"FETCH", "OR", "IXSCAN", "IXSCAN"},
+				Indexes: []string{"a_1", "b_1"},
+				Summary: "FETCH > OR[IXSCAN(a_1), IXSCAN(b_1)]",
+			},
+		},
+		{
+			name: "Test Id Lookup",
+			reply: bson.D{
+				{Key: "queryPlanner", Value: bson.D{{Key: "winningPlan", Value: bson.D{{Key: "stage", Value: "IDHACK"}}}}},
+			},
+			want: database.Plan{Stages: []string{"IDHACK"}, Indexes: []string{"_id_"}, Summary: "IDHACK(_id_)"},
+		},
+		{
+			name:  "Test Missing Plan",
+			reply: bson.D{{Key: "ok", Value: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := bson.Marshal(tt.reply)
+			require.NoError(t, err)
+
+			got, err := parseExplain(raw)
+			require.NoError(t, err)
+			require.JSONEq(t, string(mustExtJSON(t, tt.reply)), string(got.Raw))
+			got.Raw = nil
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func mustExtJSON(t *testing.T, doc bson.D) []byte {
+	b, err := bson.MarshalExtJSON(doc, false, false)
+	require.NoError(t, err)
+	return b
+}
+
+func Test_findCommand(t *testing.T) {
+	op := &database.OpInfo{
+		Collection: "users",
+		Operation:  database.OpFindOne,
+		Filter:     bson.D{{Key: "email", Value: "a@b.c"}},
+		Sort:       bson.D{{Key: "name", Value: 1}},
+		Limit:      1,
+		Offset:     5,
+	}
+	require.Equal(t, bson.D{
+		{Key: "find", Value: "users"},
+		{Key: "filter", Value: bson.D{{Key: "email", Value: "a@b.c"}}},
+		{Key: "sort", Value: bson.D{{Key: "name", Value: 1}}},
+		{Key: "limit", Value: int64(1)},
+		{Key: "skip", Value: int64(5)},
+	}, findCommand(op))
+
+	require.Equal(t, bson.D{{Key: "find", Value: "users"}, {Key: "filter", Value: bson.D{}}},
+		findCommand(&database.OpInfo{Collection: "users", Operation: database.OpCount}))
+}
+
+func Test_explainable(t *testing.T) {
+	for _, operation := range []string{database.OpFind, database.OpFindOne, database.OpCount, database.OpDistinct, database.OpAggregate} {
+		require.True(t, explainable(&database.OpInfo{Operation: operation}), operation)
+	}
+	for _, operation := range []string{
+		database.OpInsert, database.OpUpdate, database.OpUpdateMany, database.OpDelete,
+		database.OpDeleteMany, database.OpExplain, database.OpWatch,
+	} {
+		require.False(t, explainable(&database.OpInfo{Operation: operation}), operation)
+	}
+}
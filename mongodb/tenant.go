@@ -0,0 +1,51 @@
+package mongodb
+
+import (
+	"context"
+	"slices"
+
+	"github.com/neghi-go/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// tenantOf returns the tenant of ctx for models with a tenant field, empty for
+// the others.
+func (m *MongoModel[T]) tenantOf(ctx context.Context) (string, error) {
+	if m.tenant == "" {
+		return "", nil
+	}
+	tenant, ok := database.TenantFrom(ctx)
+	if !ok {
+		return "", database.ErrNoTenant
+	}
+	return tenant, nil
+}
+
+// scope restricts the current query to the tenant of the model context and
+// returns it. It must be called before the query state is captured, and
+// resets it when the context has no tenant or the query has an invalid param.
+func (m *MongoModel[T]) scope() (string, error) {
+	if err := m.queryErr; err != nil {
+		m.reset()
+		return "", err
+	}
+	tenant, err := m.tenantOf(m.ctx)
+	if err != nil {
+		m.reset()
+		return "", err
+	}
+	if tenant != "" {
+		m.filter = append(bson.D{{Key: m.tenant, Value: tenant}}, m.filter...)
+	}
+	return tenant, nil
+}
+
+// stamp sets the tenant field of an encoded document, so inserts land in the
+// tenant and updates can't move a document out of it.
+func (m *MongoModel[T]) stamp(d bson.D, tenant string) bson.D {
+	if tenant == "" {
+		return d
+	}
+	d = slices.DeleteFunc(d, func(e bson.E) bool { return e.Key == m.tenant })
+	return append(d, bson.E{Key: m.tenant, Value: tenant})
+}
@@ -0,0 +1,84 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/neghi-go/database"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func Test_scope(t *testing.T) {
+	type doc struct {
+		Tenant string `db:"tenant_id,tenant"`
+		Name   string `db:"name"`
+	}
+	fields, err := database.FieldsOf(doc{})
+	require.NoError(t, err)
+	m := &MongoModel[doc]{tenant: "tenant_id", fields: fields, filter: bson.D{{Key: "name", Value: "a"}}}
+
+	m.WithContext(context.Background())
+	_, err = m.scope()
+	require.ErrorIs(t, err, database.ErrNoTenant)
+	require.Empty(t, m.filter, "a refused query is reset")
+
+	m.WithContext(database.WithTenant(context.Background(), "acme"))
+	m.filter = bson.D{{Key: "name", Value: "a"}}
+	tenant, err := m.scope()
+	require.NoError(t, err)
+	require.Equal(t, "acme", tenant)
+	require.Equal(t, bson.D{{Key: "tenant_id", Value: "acme"}, {Key: "name", Value: "a"}}, m.filter)
+
+	require.Equal(t, bson.D{{Key: "name", Value: "a"}, {Key: "tenant_id", Value: "acme"}},
+		m.stamp(bson.D{{Key: "tenant_id", Value: "other"}, {Key: "name", Value: "a"}}, "acme"))
+
+	m.Set("tenant_id", "other")
+	require.Error(t, m.updateErr)
+}
+
+func Test_scopeWithoutTenantField(t *testing.T) {
+	m := &MongoModel[struct{}]{ctx: context.Background(), filter: bson.D{}}
+	tenant, err := m.scope()
+	require.NoError(t, err)
+	require.Empty(t, tenant)
+	require.Empty(t, m.filter)
+	require.Equal(t, bson.D{{Key: "name", Value: "a"}}, m.stamp(bson.D{{Key: "name", Value: "a"}}, tenant))
+}
+
+func Test_scopeUnknownNames(t *testing.T) {
+	m := &MongoModel[struct{}]{ctx: context.Background(), filter: bson.D{}, scopes: &database.Scopes{}}
+
+	m.Query(database.UseScope("closed"))
+	_, err := m.scope()
+	require.Error(t, err)
+
+	m.Query(database.WithPreload("Author"))
+	_, err = m.scope()
+	require.Error(t, err)
+
+	m.Query()
+	_, err = m.scope()
+	require.NoError(t, err, "a refused query is reset")
+}
+
+func Test_forRetriesRegistration(t *testing.T) {
+	// the client connects lazily, and the model fails before reaching it
+	client, err := mongo.Connect(options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	require.NoError(t, err)
+	router := &Router{client: client, name: func(tenant string) string { return tenant }, dbs: map[string]*mongoDatabase{}}
+
+	type doc struct {
+		Name string `db:"name,index=up"`
+	}
+	model := RegisterTenantModel(router, "docs", doc{})
+	acme := database.WithTenant(context.Background(), "acme")
+
+	_, err = model.For(acme)
+	require.Error(t, err)
+	require.Empty(t, model.models, "a failed registration is not kept")
+	_, err = model.For(acme)
+	require.Error(t, err)
+}
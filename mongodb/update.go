@@ -0,0 +1,155 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/neghi-go/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var errEmptyUpdate = errors.New("error: no update operators given")
+
+// addUpdate records field under the update operator op, the first invalid
+// field being reported by Apply.
+func (m *MongoModel[T]) addUpdate(op, field string, value any) database.Query[T] {
+	if m.updateErr != nil {
+		return m
+	}
+	f, ok := database.LookupField(m.fields, field)
+	if !ok {
+		m.updateErr = fmt.Errorf("error: unknown field %s", field)
+		return m
+	}
+	if f.Tenant {
+		m.updateErr = fmt.Errorf("error: tenant field %s cannot be updated", field)
+		return m
+	}
+	if f.Encrypted {
+		if field != f.Key || op != "$set" && op != "$unset" {
+			m.updateErr = fmt.Errorf("error: encrypted field %s can only be set or unset as a whole", field)
+			return m
+		}
+		if op == "$set" {
+			v, err := database.EncryptValue(f.Key, value, f.Deterministic)
+			if err != nil {
+				m.updateErr = err
+				return m
+			}
+			value = v
+		}
+	}
+	for i, e := range m.update {
+		if e.Key == op {
+			m.update[i].Value = append(e.Value.(bson.D), bson.E{Key: field, Value: value})
+			return m
+		}
+	}
+	m.update = append(m.update, bson.E{Key: op, Value: bson.D{{Key: field, Value: value}}})
+	return m
+}
+
+// eachValue wraps several values in $each for $push and $addToSet.
+func eachValue(values []any) any {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return bson.D{{Key: "$each", Value: bson.A(values)}}
+}
+
+// Set implements database.Query.
+func (m *MongoModel[T]) Set(field string, value any) database.Query[T] {
+	return m.addUpdate("$set", field, value)
+}
+
+// Unset implements database.Query.
+func (m *MongoModel[T]) Unset(field string) database.Query[T] {
+	return m.addUpdate("$unset", field, "")
+}
+
+// Inc implements database.Query.
+func (m *MongoModel[T]) Inc(field string, by any) database.Query[T] {
+	return m.addUpdate("$inc", field, by)
+}
+
+// Push implements database.Query.
+func (m *MongoModel[T]) Push(field string, values ...any) database.Query[T] {
+	return m.addUpdate("$push", field, eachValue(values))
+}
+
+// Pull implements database.Query.
+func (m *MongoModel[T]) Pull(field string, value any) database.Query[T] {
+	return m.addUpdate("$pull", field, value)
+}
+
+// AddToSet implements database.Query.
+func (m *MongoModel[T]) AddToSet(field string, values ...any) database.Query[T] {
+	return m.addUpdate("$addToSet", field, eachValue(values))
+}
+
+// Min implements database.Query.
+func (m *MongoModel[T]) Min(field string, value any) database.Query[T] {
+	return m.addUpdate("$min", field, value)
+}
+
+// Max implements database.Query.
+func (m *MongoModel[T]) Max(field string, value any) database.Query[T] {
+	return m.addUpdate("$max", field, value)
+}
+
+// CurrentDate implements database.Query.
+func (m *MongoModel[T]) CurrentDate(field string) database.Query[T] {
+	return m.addUpdate("$currentDate", field, true)
+}
+
+// Apply implements database.Query.
+func (m *MongoModel[T]) Apply() error {
+	if _, err := m.scope(); err != nil {
+		return err
+	}
+	ctx, op, filter, update, err := m.ctx, m.opInfo(database.OpUpdate), m.filter, m.update, m.updateErr
+	opts := options.UpdateOne().SetCollation(m.collation)
+	m.reset()
+
+	if err != nil {
+		return err
+	}
+	if len(update) == 0 {
+		return errEmptyUpdate
+	}
+	return m.intercept(ctx, op, func(ctx context.Context) error {
+		result, err := m.client.UpdateOne(ctx, filter, update, opts)
+		if err != nil {
+			return err
+		}
+		op.Docs = result.MatchedCount
+		return nil
+	})
+}
+
+// ApplyMany implements database.Query.
+func (m *MongoModel[T]) ApplyMany() error {
+	if _, err := m.scope(); err != nil {
+		return err
+	}
+	ctx, op, filter, update, err := m.ctx, m.opInfo(database.OpUpdateMany), m.filter, m.update, m.updateErr
+	opts := options.UpdateMany().SetCollation(m.collation)
+	m.reset()
+
+	if err != nil {
+		return err
+	}
+	if len(update) == 0 {
+		return errEmptyUpdate
+	}
+	return m.intercept(ctx, op, func(ctx context.Context) error {
+		result, err := m.client.UpdateMany(ctx, filter, update, opts)
+		if err != nil {
+			return err
+		}
+		op.Docs = result.MatchedCount
+		return nil
+	})
+}
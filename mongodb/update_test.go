@@ -0,0 +1,75 @@
+package mongodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/neghi-go/database"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestUpdateBuilder(t *testing.T) {
+	type profile struct {
+		Name    string            `db:"name"`
+		Visits  int               `db:"visits"`
+		Tags    []string          `db:"tags"`
+		Seen    time.Time         `db:"seen"`
+		Address map[string]string `db:"address"`
+	}
+	fields, err := database.FieldsOf(profile{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		build   func(q database.Query[profile]) database.Query[profile]
+		want    bson.D
+		wantErr bool
+	}{
+		{
+			name: "Test Operators",
+			build: func(q database.Query[profile]) database.Query[profile] {
+				return q.Set("name", "Ann").Set("address.city", "Oslo").Inc("visits", 1).
+					Push("tags", "a").AddToSet("tags", "b", "c").CurrentDate("seen").Unset("address.zip")
+			},
+			want: bson.D{
+				{Key: "$set", Value: bson.D{{Key: "name", Value: "Ann"}, {Key: "address.city", Value: "Oslo"}}},
+				{Key: "$inc", Value: bson.D{{Key: "visits", Value: 1}}},
+				{Key: "$push", Value: bson.D{{Key: "tags", Value: "a"}}},
+				{Key: "$addToSet", Value: bson.D{{Key: "tags", Value: bson.D{{Key: "$each", Value: bson.A{"b", "c"}}}}}},
+				{Key: "$currentDate", Value: bson.D{{Key: "seen", Value: true}}},
+				{Key: "$unset", Value: bson.D{{Key: "address.zip", Value: ""}}},
+			},
+		},
+		{
+			name: "Test Bounds",
+			build: func(q database.Query[profile]) database.Query[profile] {
+				return q.Min("visits", 0).Max("visits", 10).Pull("tags", "x")
+			},
+			want: bson.D{
+				{Key: "$min", Value: bson.D{{Key: "visits", Value: 0}}},
+				{Key: "$max", Value: bson.D{{Key: "visits", Value: 10}}},
+				{Key: "$pull", Value: bson.D{{Key: "tags", Value: "x"}}},
+			},
+		},
+		{
+			name: "Test Unknown Field",
+			build: func(q database.Query[profile]) database.Query[profile] {
+				return q.Set("name", "Ann").Set("nickname", "A")
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MongoModel[profile]{fields: fields}
+			tt.build(m)
+			if tt.wantErr {
+				require.Error(t, m.updateErr)
+				return
+			}
+			require.NoError(t, m.updateErr)
+			require.Equal(t, tt.want, m.update)
+		})
+	}
+}
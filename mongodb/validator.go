@@ -0,0 +1,247 @@
+package mongodb
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/neghi-go/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ValidationLevel is how strictly mongo applies a collection validator.
+type ValidationLevel string
+
+const (
+	// ValidationStrict validates every insert and update
+	ValidationStrict ValidationLevel = "strict"
+	// ValidationModerate skips updates to documents that were already invalid
+	ValidationModerate ValidationLevel = "moderate"
+)
+
+// ValidationAction is what mongo does with a document failing validation.
+type ValidationAction string
+
+const (
+	ValidationError ValidationAction = "error"
+	ValidationWarn  ValidationAction = "warn"
+)
+
+// WithValidation installs a $jsonSchema validator derived from the model on
+// the collection, creating the collection if it does not exist yet.
+func WithValidation(level ValidationLevel, action ValidationAction) RegisterOption {
+	return func(c *registerConfig) {
+		c.validate = true
+		c.level = level
+		c.action = action
+	}
+}
+
+var (
+	tTime     = reflect.TypeOf(time.Time{})
+	tObjectID = reflect.TypeOf(bson.ObjectID{})
+)
+
+// JSONSchema derives the $jsonSchema validator document for a model from its
+// db tags.
+func JSONSchema(model interface{}) (bson.D, error) {
+	fields, err := database.FieldsOf(model)
+	if err != nil {
+		return nil, err
+	}
+	required := bson.A{}
+	properties := bson.D{}
+	for _, f := range fields {
+		prop, err := fieldSchema(f)
+		if err != nil {
+			return nil, err
+		}
+		properties = append(properties, bson.E{Key: f.Key, Value: prop})
+		if f.Required {
+			required = append(required, f.Key)
+		}
+	}
+	schema := bson.D{{Key: "bsonType", Value: "object"}}
+	if len(required) > 0 {
+		schema = append(schema, bson.E{Key: "required", Value: required})
+	}
+	return append(schema, bson.E{Key: "properties", Value: properties}), nil
+}
+
+func fieldSchema(f database.Field) (bson.D, error) {
+	// the rules of an encrypted field apply to its plaintext, which the
+	// server never sees, so it only checks the value was encrypted
+	if f.Encrypted {
+		return bson.D{
+			{Key: "bsonType", Value: "string"},
+			{Key: "pattern", Value: "^enc:"},
+		}, nil
+	}
+	t := f.Type
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+
+	var types bson.A
+	switch {
+	case f.MongoID:
+		types = bson.A{"objectId"}
+	default:
+		types = bsonTypes(t)
+	}
+	// nil slices and maps are written as null
+	if nullable || t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 || t.Kind() == reflect.Map {
+		types = append(types, "null")
+	}
+
+	res := bson.D{}
+	switch len(types) {
+	case 0:
+	case 1:
+		res = append(res, bson.E{Key: "bsonType", Value: types[0]})
+	default:
+		res = append(res, bson.E{Key: "bsonType", Value: types})
+	}
+
+	// optional fields are written with their zero value when unset, which
+	// the rules only constrain when it is not
+	zero, hasZero := zeroValue(t)
+	optional := !f.Required && !f.MongoID && hasZero
+
+	if len(f.Enum) > 0 {
+		enum, err := f.EnumValues()
+		if err != nil {
+			return nil, err
+		}
+		if optional {
+			enum = append(enum, zero)
+		}
+		if nullable {
+			enum = append(enum, nil)
+		}
+		res = append(res, bson.E{Key: "enum", Value: bson.A(enum)})
+	}
+
+	minKey, maxKey := "minimum", "maximum"
+	switch t.Kind() {
+	case reflect.String:
+		minKey, maxKey = "minLength", "maxLength"
+	case reflect.Slice, reflect.Array:
+		minKey, maxKey = "minItems", "maxItems"
+	}
+	bounds := bson.D{}
+	if f.Min != nil {
+		bounds = append(bounds, bson.E{Key: minKey, Value: boundValue(minKey, *f.Min)})
+	}
+	if f.Max != nil {
+		bounds = append(bounds, bson.E{Key: maxKey, Value: boundValue(maxKey, *f.Max)})
+	}
+	if optional && zeroOutOfBounds(t, f.Min, f.Max) {
+		return append(res, bson.E{Key: "anyOf", Value: bson.A{
+			bson.D{{Key: "enum", Value: bson.A{zero}}},
+			bounds,
+		}}), nil
+	}
+	return append(res, bounds...), nil
+}
+
+// zeroValue returns the zero value of a scalar type as EnumValues parses it.
+func zeroValue(t reflect.Type) (interface{}, bool) {
+	switch t.Kind() {
+	case reflect.String:
+		return "", true
+	case reflect.Bool:
+		return false, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(0), true
+	case reflect.Float32, reflect.Float64:
+		return float64(0), true
+	default:
+		return nil, false
+	}
+}
+
+// zeroOutOfBounds reports whether the zero value of t fails min or max.
+func zeroOutOfBounds(t reflect.Type, lo, hi *float64) bool {
+	switch t.Kind() {
+	case reflect.String:
+		return lo != nil && *lo > 0
+	case reflect.Slice, reflect.Array, reflect.Bool:
+		return false
+	default:
+		return lo != nil && *lo > 0 || hi != nil && *hi < 0
+	}
+}
+
+// bsonTypes lists the bson types the driver may write for a Go type. Go ints
+// are written as int32 when they fit, so wide integers accept both.
+func bsonTypes(t reflect.Type) bson.A {
+	switch t {
+	case tUUID:
+		return bson.A{"binData"}
+	case tTime:
+		return bson.A{"date"}
+	case tObjectID:
+		return bson.A{"objectId"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return bson.A{"string"}
+	case reflect.Bool:
+		return bson.A{"bool"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return bson.A{"int"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return bson.A{"int", "long"}
+	case reflect.Float32, reflect.Float64:
+		return bson.A{"double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return bson.A{"binData"}
+		}
+		return bson.A{"array"}
+	case reflect.Map, reflect.Struct:
+		return bson.A{"object"}
+	default:
+		return nil
+	}
+}
+
+// boundValue returns length and item bounds as integers, which $jsonSchema
+// requires, and numeric bounds as given.
+func boundValue(key string, v float64) interface{} {
+	if key == "minimum" || key == "maximum" {
+		return v
+	}
+	return int64(v)
+}
+
+// applyValidator installs the model's validator, with createCollection when
+// the collection is new and collMod otherwise.
+func applyValidator(ctx context.Context, conn *mongoDatabase, coll string, model interface{}, cfg registerConfig) error {
+	schema, err := JSONSchema(model)
+	if err != nil {
+		return err
+	}
+	validator := bson.D{{Key: "$jsonSchema", Value: schema}}
+
+	names, err := conn.db.ListCollectionNames(ctx, bson.D{{Key: "name", Value: coll}})
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return conn.db.CreateCollection(ctx, coll, options.CreateCollection().
+			SetValidator(validator).
+			SetValidationLevel(string(cfg.level)).
+			SetValidationAction(string(cfg.action)))
+	}
+	return conn.db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: coll},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: string(cfg.level)},
+		{Key: "validationAction", Value: string(cfg.action)},
+	}).Err()
+}
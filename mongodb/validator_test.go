@@ -0,0 +1,106 @@
+package mongodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestJSONSchema(t *testing.T) {
+	type address struct {
+		City string
+	}
+	type user struct {
+		ID        string            `db:"mongoid"`
+		UID       uuid.UUID         `db:"uid"`
+		Email     string            `db:"email,required,min=3,max=254"`
+		Role      string            `db:"role,enum=admin|member"`
+		Level     int8              `db:"level,enum=1|2|3"`
+		Plan      string            `db:"plan,required,enum=free|pro"`
+		Status    *string           `db:"status,enum=on|off"`
+		Nick      string            `db:"nick,min=2,max=20"`
+		Age       int               `db:"age,min=18"`
+		Score     float64           `db:"score,min=0,max=1"`
+		Visits    int               `db:"visits"`
+		Active    bool              `db:"active"`
+		Tags      []string          `db:"tags,max=5"`
+		Meta      map[string]string `db:"meta"`
+		Address   *address          `db:"address"`
+		CreatedAt time.Time         `db:"created_at,required"`
+		SSN       string            `db:"ssn,encrypted,min=9"`
+	}
+
+	got, err := JSONSchema(user{})
+	require.NoError(t, err)
+	require.Equal(t, bson.D{
+		{Key: "bsonType", Value: "object"},
+		{Key: "required", Value: bson.A{"email", "plan", "created_at"}},
+		{Key: "properties", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "bsonType", Value: "objectId"}}},
+			{Key: "uid", Value: bson.D{{Key: "bsonType", Value: "binData"}}},
+			{Key: "email", Value: bson.D{
+				{Key: "bsonType", Value: "string"},
+				{Key: "minLength", Value: int64(3)},
+				{Key: "maxLength", Value: int64(254)},
+			}},
+			{Key: "role", Value: bson.D{
+				{Key: "bsonType", Value: "string"},
+				{Key: "enum", Value: bson.A{"admin", "member", ""}},
+			}},
+			{Key: "level", Value: bson.D{
+				{Key: "bsonType", Value: "int"},
+				{Key: "enum", Value: bson.A{int64(1), int64(2), int64(3), int64(0)}},
+			}},
+			{Key: "plan", Value: bson.D{
+				{Key: "bsonType", Value: "string"},
+				{Key: "enum", Value: bson.A{"free", "pro"}},
+			}},
+			{Key: "status", Value: bson.D{
+				{Key: "bsonType", Value: bson.A{"string", "null"}},
+				{Key: "enum", Value: bson.A{"on", "off", "", nil}},
+			}},
+			{Key: "nick", Value: bson.D{
+				{Key: "bsonType", Value: "string"},
+				{Key: "anyOf", Value: bson.A{
+					bson.D{{Key: "enum", Value: bson.A{""}}},
+					bson.D{{Key: "minLength", Value: int64(2)}, {Key: "maxLength", Value: int64(20)}},
+				}},
+			}},
+			{Key: "age", Value: bson.D{
+				{Key: "bsonType", Value: bson.A{"int", "long"}},
+				{Key: "anyOf", Value: bson.A{
+					bson.D{{Key: "enum", Value: bson.A{int64(0)}}},
+					bson.D{{Key: "minimum", Value: float64(18)}},
+				}},
+			}},
+			{Key: "score", Value: bson.D{
+				{Key: "bsonType", Value: "double"},
+				{Key: "minimum", Value: float64(0)},
+				{Key: "maximum", Value: float64(1)},
+			}},
+			{Key: "visits", Value: bson.D{{Key: "bsonType", Value: bson.A{"int", "long"}}}},
+			{Key: "active", Value: bson.D{{Key: "bsonType", Value: "bool"}}},
+			{Key: "tags", Value: bson.D{
+				{Key: "bsonType", Value: bson.A{"array", "null"}},
+				{Key: "maxItems", Value: int64(5)},
+			}},
+			{Key: "meta", Value: bson.D{{Key: "bsonType", Value: bson.A{"object", "null"}}}},
+			{Key: "address", Value: bson.D{{Key: "bsonType", Value: bson.A{"object", "null"}}}},
+			{Key: "created_at", Value: bson.D{{Key: "bsonType", Value: "date"}}},
+			{Key: "ssn", Value: bson.D{
+				{Key: "bsonType", Value: "string"},
+				{Key: "pattern", Value: "^enc:"},
+			}},
+		}},
+	}, got)
+
+	t.Run("Test Invalid Enum", func(t *testing.T) {
+		_, err := JSONSchema(struct {
+			Level int `db:"level,enum=low|high"`
+		}{})
+		require.Error(t, err)
+	})
+}
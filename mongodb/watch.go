@@ -0,0 +1,217 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/neghi-go/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// changeEvent is the part of a change stream event the package reads.
+type changeEvent struct {
+	OperationType     string         `bson:"operationType"`
+	DocumentKey       bson.D         `bson:"documentKey"`
+	FullDocument      bson.D         `bson:"fullDocument"`
+	ClusterTime       bson.Timestamp `bson:"clusterTime"`
+	WallTime          *time.Time     `bson:"wallTime"`
+	UpdateDescription *struct {
+		UpdatedFields bson.D   `bson:"updatedFields"`
+		RemovedFields []string `bson:"removedFields"`
+	} `bson:"updateDescription"`
+}
+
+// Watch implements database.Model. Change streams need a replica set or a
+// sharded cluster. Default and named scopes apply as they do to queries, but
+// Watch only takes filters and resume tokens, so scopes holding other params
+// make it fail.
+//
+// Filters match the document after the write, and the document before it for
+// deletes. Filtered streams, tenant scoped ones included, enable pre-images on
+// the collection for that (MongoDB 6.0 or later), so a delete is still missed
+// when it removed a document before pre-images were enabled, or once its
+// pre-image expired.
+func (m *MongoModel[T]) Watch(ctx context.Context, params ...database.Params) (<-chan database.ChangeEvent[T], error) {
+	params, err := m.scopes.Expand(params)
+	if err != nil {
+		return nil, err
+	}
+	var filter bson.D
+	opts := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetFullDocumentBeforeChange(options.WhenAvailable)
+	for _, param := range params {
+		qq := param()
+		switch qq.Key() {
+		case database.QueryFilter:
+			val, ok := qq.Value().(database.FilterStruct)
+			if !ok {
+				return nil, errors.New("error: unsupported filter")
+			}
+			value, err := database.FilterValue(m.fields, val.Key(), val.Value())
+			if err != nil {
+				return nil, err
+			}
+			filter = append(filter, bson.E{Key: val.Key(), Value: filterValue(val.Key(), value)})
+		case database.QueryResumeToken:
+			val, ok := qq.Value().(string)
+			if !ok {
+				return nil, errors.New("error: unsupported resume token")
+			}
+			var token bson.Raw
+			if err := bson.UnmarshalExtJSON([]byte(val), true, &token); err != nil {
+				return nil, fmt.Errorf("error: invalid resume token: %w", err)
+			}
+			// unlike resumeAfter, startAfter also continues past an invalidate
+			opts.SetStartAfter(token)
+		default:
+			return nil, fmt.Errorf("error: %s is not supported by Watch", qq.Key())
+		}
+	}
+
+	tenant, err := m.tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tenant != "" {
+		filter = append(bson.D{{Key: m.tenant, Value: tenant}}, filter...)
+	}
+	if len(filter) > 0 {
+		if err := enablePreImages(ctx, m.conn, m.client.Name()); err != nil {
+			return nil, err
+		}
+	}
+
+	op := m.opInfo(database.OpWatch)
+	op.Filter, op.Sort, op.Limit, op.Offset, op.Query = filter, nil, 0, 0, querySummary(filter)
+
+	var stream *mongo.ChangeStream
+	err = m.intercept(ctx, op, func(ctx context.Context) (err error) {
+		stream, err = m.client.Watch(ctx, watchPipeline(filter), opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan database.ChangeEvent[T])
+	go func() {
+		defer close(events)
+		defer stream.Close(context.WithoutCancel(ctx))
+
+		for stream.Next(ctx) {
+			event := decodeChange[T](stream)
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+			if event.Err != nil || event.Operation == database.ChangeInvalidate {
+				return
+			}
+		}
+		if err := stream.Err(); err != nil && ctx.Err() == nil {
+			select {
+			case events <- database.ChangeEvent[T]{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return events, nil
+}
+
+// watchPipeline matches filter against the document after the write, or
+// before it for deletes, and against the document key for ids. Invalidate
+// events are let through so the stream doesn't end silently.
+func watchPipeline(filter bson.D) mongo.Pipeline {
+	if len(filter) == 0 {
+		return mongo.Pipeline{}
+	}
+	after := bson.D{}
+	before := bson.D{{Key: "operationType", Value: string(database.ChangeDelete)}}
+	for _, e := range filter {
+		if e.Key == "_id" {
+			after = append(after, bson.E{Key: "documentKey._id", Value: e.Value})
+			before = append(before, bson.E{Key: "documentKey._id", Value: e.Value})
+			continue
+		}
+		after = append(after, bson.E{Key: "fullDocument." + e.Key, Value: e.Value})
+		before = append(before, bson.E{Key: "fullDocumentBeforeChange." + e.Key, Value: e.Value})
+	}
+	return mongo.Pipeline{{{Key: "$match", Value: bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "operationType", Value: string(database.ChangeInvalidate)}},
+		after,
+		before,
+	}}}}}}
+}
+
+// enablePreImages makes the server keep the document before each write for
+// change streams, creating the collection when it does not exist yet.
+func enablePreImages(ctx context.Context, conn *mongoDatabase, coll string) error {
+	enabled := bson.D{{Key: "enabled", Value: true}}
+	names, err := conn.db.ListCollectionNames(ctx, bson.D{{Key: "name", Value: coll}})
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return conn.db.CreateCollection(ctx, coll, options.CreateCollection().SetChangeStreamPreAndPostImages(enabled))
+	}
+	return conn.db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: coll},
+		{Key: "changeStreamPreAndPostImages", Value: enabled},
+	}).Err()
+}
+
+func decodeChange[T any](stream *mongo.ChangeStream) database.ChangeEvent[T] {
+	var raw changeEvent
+	if err := stream.Decode(&raw); err != nil {
+		return database.ChangeEvent[T]{Err: err}
+	}
+	token, err := bson.MarshalExtJSON(stream.ResumeToken(), true, false)
+	if err != nil {
+		return database.ChangeEvent[T]{Err: err}
+	}
+	return changeFromEvent[T](raw, string(token))
+}
+
+func changeFromEvent[T any](raw changeEvent, token string) database.ChangeEvent[T] {
+	event := database.ChangeEvent[T]{
+		Operation:   database.ChangeOp(raw.OperationType),
+		ResumeToken: token,
+		Time:        time.Unix(int64(raw.ClusterTime.T), 0),
+	}
+	if raw.WallTime != nil {
+		event.Time = *raw.WallTime
+	}
+	for _, e := range raw.DocumentKey {
+		if e.Key == "_id" {
+			key, err := convertBsonValue(e.Value)
+			if err != nil {
+				event.Err = err
+				return event
+			}
+			event.Key = key
+		}
+	}
+	if raw.UpdateDescription != nil {
+		for _, e := range raw.UpdateDescription.UpdatedFields {
+			event.UpdatedFields = append(event.UpdatedFields, e.Key)
+		}
+		event.UpdatedFields = append(event.UpdatedFields, raw.UpdateDescription.RemovedFields...)
+	}
+	// the document is gone by the time an update is looked up when it was
+	// deleted right after, in which case fullDocument is null
+	if len(raw.FullDocument) > 0 {
+		var doc T
+		if err := convertFromBson(&doc, raw.FullDocument); err != nil {
+			event.Err = err
+			return event
+		}
+		event.Document = &doc
+	}
+	return event
+}
@@ -0,0 +1,81 @@
+package mongodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/neghi-go/database"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func Test_watchPipeline(t *testing.T) {
+	id := bson.NewObjectID()
+	require.Equal(t, mongo.Pipeline{}, watchPipeline(nil))
+	require.Equal(t, mongo.Pipeline{{{Key: "$match", Value: bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "operationType", Value: "invalidate"}},
+		bson.D{{Key: "fullDocument.status", Value: "open"}, {Key: "documentKey._id", Value: id}},
+		bson.D{
+			{Key: "operationType", Value: "delete"},
+			{Key: "fullDocumentBeforeChange.status", Value: "open"},
+			{Key: "documentKey._id", Value: id},
+		},
+	}}}}}}, watchPipeline(bson.D{{Key: "status", Value: "open"}, {Key: "_id", Value: id}}))
+}
+
+func Test_changeFromEvent(t *testing.T) {
+	type order struct {
+		ID     string `db:"mongoid"`
+		Status string `db:"status"`
+	}
+	id := bson.NewObjectID()
+	wall := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		raw  changeEvent
+		want database.ChangeEvent[order]
+	}{
+		{
+			name: "Test Update",
+			raw: changeEvent{
+				OperationType: "update",
+				DocumentKey:   bson.D{{Key: "_id", Value: id}},
+				FullDocument:  bson.D{{Key: "_id", Value: id}, {Key: "status", Value: "paid"}},
+				ClusterTime:   bson.Timestamp{T: uint32(wall.Unix())},
+				UpdateDescription: &struct {
+					UpdatedFields bson.D   `bson:"updatedFields"`
+					RemovedFields []string `bson:"removedFields"`
+				}{UpdatedFields: bson.D{{Key: "status", Value: "paid"}}, RemovedFields: []string{"note"}},
+			},
+			want: database.ChangeEvent[order]{
+				Operation:     database.ChangeUpdate,
+				Key:           id.Hex(),
+				Document:      &order{ID: id.Hex(), Status: "paid"},
+				UpdatedFields: []string{"status", "note"},
+				ResumeToken:   "token",
+				Time:          time.Unix(wall.Unix(), 0),
+			},
+		},
+		{
+			name: "Test Delete",
+			raw: changeEvent{
+				OperationType: "delete",
+				DocumentKey:   bson.D{{Key: "_id", Value: id}},
+				WallTime:      &wall,
+			},
+			want: database.ChangeEvent[order]{
+				Operation:   database.ChangeDelete,
+				Key:         id.Hex(),
+				ResumeToken: "token",
+				Time:        wall,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, changeFromEvent[order](tt.raw, "token"))
+		})
+	}
+}
@@ -0,0 +1,167 @@
+// Package outbox stores domain events in the same database as the writes
+// producing them, and relays them to a message broker once committed.
+//
+// Events are enqueued with the context of the transaction doing the write, so
+// they are only stored, and later published, if it commits:
+//
+//	err := conn.WithTransaction(ctx, func(ctx context.Context) error {
+//		if err := orders.WithContext(ctx).Save(order); err != nil {
+//			return err
+//		}
+//		return box.Enqueue(ctx, outbox.Event{Topic: "order.created", Key: order.ID, Payload: payload})
+//	})
+//
+// A Relay then delivers the pending events, at least once: a publisher that
+// is slower than the lease may see an event again, so consumers should
+// deduplicate on Event.ID.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const defaultCollection = "_outbox"
+
+// Event states.
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	// StatusFailed marks events that ran out of attempts. They are kept for
+	// inspection and can be retried by setting them back to pending.
+	StatusFailed = "failed"
+)
+
+var (
+	ErrNoTopic = errors.New("error: outbox event has no topic")
+)
+
+// Event is a message waiting in the outbox. ID and CreatedAt are set by
+// Enqueue.
+type Event struct {
+	ID        string
+	Topic     string
+	Key       string
+	Payload   []byte
+	Headers   map[string]string
+	CreatedAt time.Time
+	// Attempts counts the failed deliveries so far
+	Attempts int
+}
+
+// record is the stored form of an event.
+type record struct {
+	ID            bson.ObjectID     `bson:"_id"`
+	Topic         string            `bson:"topic"`
+	Key           string            `bson:"key,omitempty"`
+	Payload       []byte            `bson:"payload"`
+	Headers       map[string]string `bson:"headers,omitempty"`
+	CreatedAt     time.Time         `bson:"created_at"`
+	Status        string            `bson:"status"`
+	Attempts      int               `bson:"attempts"`
+	NextAttemptAt time.Time         `bson:"next_attempt_at"`
+	LeaseOwner    string            `bson:"lease_owner,omitempty"`
+	LeaseUntil    *time.Time        `bson:"lease_until,omitempty"`
+	SentAt        *time.Time        `bson:"sent_at,omitempty"`
+	LastError     string            `bson:"last_error,omitempty"`
+}
+
+func (r record) event() Event {
+	return Event{
+		ID:        r.ID.Hex(),
+		Topic:     r.Topic,
+		Key:       r.Key,
+		Payload:   r.Payload,
+		Headers:   r.Headers,
+		CreatedAt: r.CreatedAt,
+		Attempts:  r.Attempts,
+	}
+}
+
+type Outbox struct {
+	coll      *mongo.Collection
+	retention time.Duration
+	now       func() time.Time
+}
+
+type Option func(*Outbox)
+
+// WithCollection stores the events in name instead of _outbox.
+func WithCollection(name string) Option {
+	return func(o *Outbox) {
+		o.coll = o.coll.Database().Collection(name)
+	}
+}
+
+// WithRetention removes sent events once they are older than d. Sent events
+// are kept forever by default.
+func WithRetention(d time.Duration) Option {
+	return func(o *Outbox) {
+		o.retention = d
+	}
+}
+
+func New(db *mongo.Database, opts ...Option) *Outbox {
+	o := &Outbox{
+		coll: db.Collection(defaultCollection),
+		now:  time.Now,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Setup creates the indexes the relay relies on. It is safe to call on every
+// start.
+func (o *Outbox) Setup(ctx context.Context) error {
+	indexes := []mongo.IndexModel{{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+	}}
+	if o.retention > 0 {
+		// only sent events have sent_at, so pending ones never expire
+		indexes = append(indexes, mongo.IndexModel{
+			Keys:    bson.D{{Key: "sent_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(o.retention.Seconds())),
+		})
+	}
+	_, err := o.coll.Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+// Enqueue stores events for delivery. Called with the context of a
+// transaction, the events are only stored if it commits.
+func (o *Outbox) Enqueue(ctx context.Context, events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	now := o.now().UTC()
+	docs := make([]record, 0, len(events))
+	for _, e := range events {
+		if e.Topic == "" {
+			return ErrNoTopic
+		}
+		docs = append(docs, record{
+			ID:            bson.NewObjectID(),
+			Topic:         e.Topic,
+			Key:           e.Key,
+			Payload:       e.Payload,
+			Headers:       e.Headers,
+			CreatedAt:     now,
+			Status:        StatusPending,
+			NextAttemptAt: now,
+		})
+	}
+	_, err := o.coll.InsertMany(ctx, docs)
+	return err
+}
+
+// Pending returns the number of events waiting for delivery.
+func (o *Outbox) Pending(ctx context.Context) (int64, error) {
+	return o.coll.CountDocuments(ctx, bson.D{{Key: "status", Value: StatusPending}})
+}
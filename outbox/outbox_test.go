@@ -0,0 +1,249 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neghi-go/database/mongodb"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var test_url string
+
+func TestMain(m *testing.M) {
+	// a single node replica set, as transactions need one
+	client := testcontainers.ContainerRequest{
+		Image:        "mongo:8.0",
+		ExposedPorts: []string{"27017/tcp"},
+		Cmd:          []string{"--replSet", "rs0", "--bind_ip_all"},
+		WaitingFor:   wait.ForLog("Waiting for connections"),
+	}
+	mongoClient, err := testcontainers.GenericContainer(context.Background(), testcontainers.GenericContainerRequest{
+		ContainerRequest: client,
+		Started:          true,
+	})
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	code, _, err := mongoClient.Exec(context.Background(), []string{"mongosh", "--quiet", "--eval",
+		"rs.initiate(); while (!db.hello().isWritablePrimary) { sleep(100) }"})
+	if err != nil || code != 0 {
+		fmt.Println("replica set initiation failed:", code, err)
+		testcontainers.TerminateContainer(mongoClient)
+		os.Exit(1)
+	}
+
+	endpoint, _ := mongoClient.Endpoint(context.Background(), "")
+	// the member is known by its container hostname, unreachable from here
+	test_url = endpoint + "/?directConnection=true"
+	exitVal := m.Run()
+	testcontainers.TerminateContainer(mongoClient)
+	os.Exit(exitVal)
+}
+
+func testDB(t *testing.T, name string) *mongo.Database {
+	conn, err := mongodb.New("mongodb://"+test_url, name)
+	require.NoError(t, err)
+	return conn.Database()
+}
+
+// recorder is a publisher remembering the events it received, failing while
+// fail is set.
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+	fail   error
+}
+
+func (r *recorder) Publish(ctx context.Context, e Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail != nil {
+		return r.fail
+	}
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *recorder) topics() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var res []string
+	for _, e := range r.events {
+		res = append(res, e.Topic)
+	}
+	return res
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	b := ExponentialBackoff(time.Second, 10*time.Second)
+	var got []time.Duration
+	for attempt := 1; attempt <= 6; attempt++ {
+		got = append(got, b(attempt))
+	}
+	require.Equal(t, []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second,
+	}, got)
+}
+
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+	box := New(testDB(t, "outbox_relay"))
+	require.NoError(t, box.Setup(ctx))
+
+	require.ErrorIs(t, box.Enqueue(ctx, Event{Key: "1"}), ErrNoTopic)
+	require.NoError(t, box.Enqueue(ctx,
+		Event{Topic: "order.created", Key: "1", Payload: []byte(`{"id":1}`), Headers: map[string]string{"v": "1"}},
+		Event{Topic: "order.paid", Key: "1"},
+	))
+	pending, err := box.Pending(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), pending)
+
+	pub := &recorder{}
+	n, err := NewRelay(box, pub).RunOnce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, []string{"order.created", "order.paid"}, pub.topics())
+	require.Equal(t, []byte(`{"id":1}`), pub.events[0].Payload)
+	require.Equal(t, map[string]string{"v": "1"}, pub.events[0].Headers)
+	require.NotEmpty(t, pub.events[0].ID)
+
+	pending, err = box.Pending(ctx)
+	require.NoError(t, err)
+	require.Zero(t, pending)
+
+	n, err = NewRelay(box, pub).RunOnce(ctx)
+	require.NoError(t, err)
+	require.Zero(t, n, "sent events are not delivered again")
+}
+
+func TestEnqueueInTransaction(t *testing.T) {
+	ctx := context.Background()
+	conn, err := mongodb.New("mongodb://"+test_url, "outbox_transactions")
+	require.NoError(t, err)
+	box := New(conn.Database())
+	require.NoError(t, box.Setup(ctx))
+
+	t.Run("Commit", func(t *testing.T) {
+		require.NoError(t, conn.WithTransaction(ctx, func(ctx context.Context) error {
+			return box.Enqueue(ctx, Event{Topic: "order.created"})
+		}))
+		pending, err := box.Pending(ctx)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), pending)
+		_, err = box.coll.DeleteMany(ctx, bson.D{})
+		require.NoError(t, err)
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		failed := errors.New("payment declined")
+		err := conn.WithTransaction(ctx, func(ctx context.Context) error {
+			if err := box.Enqueue(ctx, Event{Topic: "order.paid"}); err != nil {
+				return err
+			}
+			return failed
+		})
+		require.ErrorIs(t, err, failed)
+		count, err := box.coll.CountDocuments(ctx, bson.D{})
+		require.NoError(t, err)
+		require.Zero(t, count, "the event is rolled back with the transaction")
+	})
+}
+
+func TestRelayRetries(t *testing.T) {
+	ctx := context.Background()
+	box := New(testDB(t, "outbox_retries"))
+	now := time.Now()
+	box.now = func() time.Time { return now }
+	require.NoError(t, box.Enqueue(ctx, Event{Topic: "order.created"}))
+
+	pub := &recorder{fail: errors.New("broker down")}
+	relay := NewRelay(box, pub, WithMaxAttempts(2), WithBackoff(ExponentialBackoff(time.Minute, time.Hour)))
+
+	n, err := relay.RunOnce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	n, err = relay.RunOnce(ctx)
+	require.NoError(t, err)
+	require.Zero(t, n, "waiting for the backoff")
+
+	now = now.Add(time.Minute)
+	n, err = relay.RunOnce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	var rec record
+	require.NoError(t, box.coll.FindOne(ctx, bson.D{}).Decode(&rec))
+	require.Equal(t, StatusFailed, rec.Status)
+	require.Equal(t, 2, rec.Attempts)
+	require.Equal(t, "broker down", rec.LastError)
+	require.Empty(t, rec.LeaseOwner)
+}
+
+func TestRelayLeases(t *testing.T) {
+	ctx := context.Background()
+	box := New(testDB(t, "outbox_leases"))
+	require.NoError(t, box.Setup(ctx))
+	for i := 0; i < 50; i++ {
+		require.NoError(t, box.Enqueue(ctx, Event{Topic: fmt.Sprintf("event.%d", i)}))
+	}
+
+	pub := &recorder{}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := NewRelay(box, pub, WithBatchSize(10)).RunOnce(ctx)
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	_, err := NewRelay(box, pub).RunOnce(ctx)
+	require.NoError(t, err)
+
+	topics := pub.topics()
+	require.Len(t, topics, 50)
+	seen := map[string]bool{}
+	for _, topic := range topics {
+		require.False(t, seen[topic], "%s published twice", topic)
+		seen[topic] = true
+	}
+}
+
+func TestRelayExpiredLease(t *testing.T) {
+	ctx := context.Background()
+	box := New(testDB(t, "outbox_expired"))
+	now := time.Now()
+	box.now = func() time.Time { return now }
+	require.NoError(t, box.Enqueue(ctx, Event{Topic: "order.created"}))
+
+	// a relay that died after claiming the event
+	crashed := NewRelay(box, &recorder{}, WithLease(time.Minute))
+	_, err := crashed.claim(ctx)
+	require.NoError(t, err)
+
+	pub := &recorder{}
+	relay := NewRelay(box, pub)
+	n, err := relay.RunOnce(ctx)
+	require.NoError(t, err)
+	require.Zero(t, n, "the event is leased")
+
+	now = now.Add(time.Minute)
+	n, err = relay.RunOnce(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, []string{"order.created"}, pub.topics())
+}
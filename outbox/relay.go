@@ -0,0 +1,214 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Publisher delivers events to a broker. An error leaves the event pending
+// for a later attempt.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// PublisherFunc adapts a function to Publisher.
+type PublisherFunc func(ctx context.Context, e Event) error
+
+// Publish implements Publisher.
+func (f PublisherFunc) Publish(ctx context.Context, e Event) error {
+	return f(ctx, e)
+}
+
+// Backoff returns how long to wait before the given attempt, counted from 1.
+type Backoff func(attempt int) time.Duration
+
+// ExponentialBackoff doubles the delay after every failure, starting at base
+// and capped at max.
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		return min(d, max)
+	}
+}
+
+// Relay moves pending events from an outbox to a publisher. Several relays
+// may run on the same outbox: each event is leased to one of them while it
+// is being published.
+type Relay struct {
+	outbox      *Outbox
+	publisher   Publisher
+	owner       string
+	lease       time.Duration
+	batch       int
+	poll        time.Duration
+	maxAttempts int
+	backoff     Backoff
+}
+
+type RelayOption func(*Relay)
+
+// WithLease sets how long an event is reserved for a relay while it publishes
+// it, 30 seconds by default. Past it, another relay may publish it again.
+func WithLease(d time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.lease = d
+	}
+}
+
+// WithBatchSize sets how many events a relay claims per round, 100 by default.
+func WithBatchSize(n int) RelayOption {
+	return func(r *Relay) {
+		r.batch = n
+	}
+}
+
+// WithPollInterval sets how long Run waits when the outbox is empty, one
+// second by default.
+func WithPollInterval(d time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.poll = d
+	}
+}
+
+// WithMaxAttempts marks events as failed after n failed deliveries, 10 by
+// default. Zero retries forever.
+func WithMaxAttempts(n int) RelayOption {
+	return func(r *Relay) {
+		r.maxAttempts = n
+	}
+}
+
+// WithBackoff sets the delay between attempts, exponential from one second up
+// to five minutes by default.
+func WithBackoff(b Backoff) RelayOption {
+	return func(r *Relay) {
+		r.backoff = b
+	}
+}
+
+// NewRelay creates a relay delivering the events of o to p.
+func NewRelay(o *Outbox, p Publisher, opts ...RelayOption) *Relay {
+	host, _ := os.Hostname()
+	r := &Relay{
+		outbox:      o,
+		publisher:   p,
+		owner:       host + "/" + uuid.NewString(),
+		lease:       30 * time.Second,
+		batch:       100,
+		poll:        time.Second,
+		maxAttempts: 10,
+		backoff:     ExponentialBackoff(time.Second, 5*time.Minute),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run relays events until ctx is done.
+func (r *Relay) Run(ctx context.Context) error {
+	if err := r.outbox.Setup(ctx); err != nil {
+		return err
+	}
+	for {
+		n, err := r.RunOnce(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil || n < r.batch {
+			// on errors as well, so a database outage doesn't spin
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(r.poll):
+			}
+		}
+	}
+}
+
+// RunOnce claims up to a batch of due events and publishes them. It returns
+// the number of events claimed, whether their delivery succeeded or not.
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	var n int
+	for n < r.batch {
+		rec, err := r.claim(ctx)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+		if err := r.deliver(ctx, rec); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// claim leases the oldest due event that no other relay holds.
+func (r *Relay) claim(ctx context.Context) (record, error) {
+	now := r.outbox.now().UTC()
+	filter := bson.D{
+		{Key: "status", Value: StatusPending},
+		{Key: "next_attempt_at", Value: bson.D{{Key: "$lte", Value: now}}},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "lease_until", Value: bson.D{{Key: "$exists", Value: false}}}},
+			bson.D{{Key: "lease_until", Value: bson.D{{Key: "$lte", Value: now}}}},
+		}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "lease_owner", Value: r.owner},
+		{Key: "lease_until", Value: now.Add(r.lease)},
+	}}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var rec record
+	err := r.outbox.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&rec)
+	return rec, err
+}
+
+// deliver publishes a claimed event and records the outcome. The updates are
+// conditioned on still holding the lease, so a relay whose lease expired
+// doesn't overwrite the outcome of the one that took over.
+func (r *Relay) deliver(ctx context.Context, rec record) error {
+	pubErr := r.publisher.Publish(ctx, rec.event())
+	now := r.outbox.now().UTC()
+	held := bson.D{{Key: "_id", Value: rec.ID}, {Key: "lease_owner", Value: r.owner}}
+	release := bson.D{{Key: "lease_owner", Value: ""}, {Key: "lease_until", Value: ""}}
+
+	if pubErr == nil {
+		_, err := r.outbox.coll.UpdateOne(ctx, held, bson.D{
+			{Key: "$set", Value: bson.D{{Key: "status", Value: StatusSent}, {Key: "sent_at", Value: now}}},
+			{Key: "$unset", Value: release},
+		})
+		return err
+	}
+
+	attempts := rec.Attempts + 1
+	set := bson.D{
+		{Key: "attempts", Value: attempts},
+		{Key: "last_error", Value: pubErr.Error()},
+		{Key: "next_attempt_at", Value: now.Add(r.backoff(attempts))},
+	}
+	if r.maxAttempts > 0 && attempts >= r.maxAttempts {
+		set = append(set, bson.E{Key: "status", Value: StatusFailed})
+	}
+	_, err := r.outbox.coll.UpdateOne(ctx, held, bson.D{
+		{Key: "$set", Value: set},
+		{Key: "$unset", Value: release},
+	})
+	return err
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrInvalidPageToken = errors.New("error: invalid or tampered page token")
+)
+
+// Page is a window of results produced by keyset pagination. NextToken and
+// PrevToken are empty when there is no page in that direction.
+type Page[T any] struct {
+	Items     []*T
+	NextToken string
+	PrevToken string
+}
+
+// Paginated is a numbered page of results together with the total number of
+// documents matching the query.
+type Paginated[T any] struct {
+	Items     []*T
+	Total     int64
+	Page      int64
+	PerPage   int64
+	PageCount int64
+	HasNext   bool
+}
+
+// NewPaginated fills in the derived page count and has-next flag for a page
+// fetched with the given 1-based page number and page size.
+func NewPaginated[T any](items []*T, total, page, perPage int64) Paginated[T] {
+	var count int64
+	if perPage > 0 {
+		count = (total + perPage - 1) / perPage
+	}
+	return Paginated[T]{
+		Items:     items,
+		Total:     total,
+		Page:      page,
+		PerPage:   perPage,
+		PageCount: count,
+		HasNext:   page < count,
+	}
+}
+
+var pageTokenKey = struct {
+	sync.RWMutex
+	key []byte
+}{key: randomKey()}
+
+func randomKey() []byte {
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// SetPageTokenKey sets the secret used to sign page tokens. Replicas serving the
+// same API must share the key, otherwise tokens issued by one replica are
+// rejected by the others. A random key is used until this is called.
+func SetPageTokenKey(key []byte) {
+	pageTokenKey.Lock()
+	defer pageTokenKey.Unlock()
+	pageTokenKey.key = append([]byte(nil), key...)
+}
+
+func pageTokenMAC(payload []byte) []byte {
+	pageTokenKey.RLock()
+	defer pageTokenKey.RUnlock()
+	mac := hmac.New(sha256.New, pageTokenKey.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// EncodePageToken signs a backend specific cursor payload and returns it as an
+// opaque url safe token.
+func EncodePageToken(payload []byte) string {
+	return base64.RawURLEncoding.EncodeToString(append(pageTokenMAC(payload), payload...))
+}
+
+// DecodePageToken verifies a token produced by EncodePageToken and returns the
+// payload it carries.
+func DecodePageToken(token string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(data) < sha256.Size {
+		return nil, ErrInvalidPageToken
+	}
+	sum, payload := data[:sha256.Size], data[sha256.Size:]
+	if !hmac.Equal(sum, pageTokenMAC(payload)) {
+		return nil, ErrInvalidPageToken
+	}
+	return payload, nil
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageToken(t *testing.T) {
+	SetPageTokenKey([]byte("test-key"))
+	token := EncodePageToken([]byte("cursor"))
+
+	tests := []struct {
+		name    string
+		token   string
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:  "Test Valid Token",
+			token: token,
+			want:  []byte("cursor"),
+		},
+		{
+			name:    "Test Tampered Token",
+			token:   token[:len(token)-1] + "A",
+			wantErr: true,
+		},
+		{
+			name:    "Test Malformed Token",
+			token:   "not a token",
+			wantErr: true,
+		},
+		{
+			name:    "Test Short Token",
+			token:   "c2hvcnQ",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodePageToken(tt.token)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidPageToken)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("Test Rotated Key", func(t *testing.T) {
+		SetPageTokenKey([]byte("other-key"))
+		_, err := DecodePageToken(token)
+		require.ErrorIs(t, err, ErrInvalidPageToken)
+	})
+}
+
+func TestNewPaginated(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int64
+		page    int64
+		perPage int64
+		want    Paginated[int]
+	}{
+		{
+			name:    "Test First Page",
+			total:   25,
+			page:    1,
+			perPage: 10,
+			want:    Paginated[int]{Total: 25, Page: 1, PerPage: 10, PageCount: 3, HasNext: true},
+		},
+		{
+			name:    "Test Last Page",
+			total:   25,
+			page:    3,
+			perPage: 10,
+			want:    Paginated[int]{Total: 25, Page: 3, PerPage: 10, PageCount: 3, HasNext: false},
+		},
+		{
+			name:    "Test Exact Fit",
+			total:   20,
+			page:    2,
+			perPage: 10,
+			want:    Paginated[int]{Total: 20, Page: 2, PerPage: 10, PageCount: 2, HasNext: false},
+		},
+		{
+			name:    "Test Empty Result",
+			total:   0,
+			page:    1,
+			perPage: 10,
+			want:    Paginated[int]{Total: 0, Page: 1, PerPage: 10, PageCount: 0, HasNext: false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, NewPaginated[int](nil, tt.total, tt.page, tt.perPage))
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package database
 
+import "fmt"
+
 type QueryKey string
 
 var (
@@ -7,6 +9,16 @@ var (
 	QuerySort   QueryKey = "sort"
 	QueryLimit  QueryKey = "limit"
 	QueryOffset QueryKey = "offset"
+	QueryBatch  QueryKey = "batch"
+
+	QueryCollation QueryKey = "collation"
+
+	QueryResumeToken QueryKey = "resume_token"
+
+	QueryPreload QueryKey = "preload"
+
+	QueryScope    QueryKey = "scope"
+	QueryUnscoped QueryKey = "unscoped"
 )
 
 type QueryStruct struct {
@@ -27,6 +39,7 @@ type Params func() QueryStruct
 type OrderStruct struct {
 	key   string
 	value OrderType
+	nulls NullsOrder
 }
 type OrderType int
 
@@ -42,6 +55,11 @@ func (o OrderStruct) Value() OrderType {
 	return o.value
 }
 
+// Nulls reports where documents with a null or missing key are placed.
+func (o OrderStruct) Nulls() NullsOrder {
+	return o.nulls
+}
+
 const (
 	ASC OrderType = iota
 	DESC
@@ -52,14 +70,80 @@ var orderTypeMap = map[OrderType]string{
 	DESC: "desc",
 }
 
-func WithOrder(key string, value OrderType) Params {
+// NullsOrder controls where null or missing values sort relative to the rest.
+// NullsDefault keeps the backend's native placement.
+type NullsOrder int
+
+const (
+	NullsDefault NullsOrder = iota
+	NullsFirst
+	NullsLast
+)
+
+// WithOrder sorts the results by key, ASC meaning smallest first. An optional
+// NullsOrder overrides where null values are placed.
+func WithOrder(key string, value OrderType, nulls ...NullsOrder) Params {
+	order := OrderStruct{
+		key:   key,
+		value: value,
+	}
+	if len(nulls) > 0 {
+		order.nulls = nulls[0]
+	}
 	return func() QueryStruct {
 		return QueryStruct{
-			key: QuerySort,
-			value: OrderStruct{
-				key:   key,
-				value: value,
-			},
+			key:   QuerySort,
+			value: order,
+		}
+	}
+}
+
+// WithOrderBy sorts the results by several keys in the given order of
+// precedence. Each key is followed by its OrderType and optionally a
+// NullsOrder, e.g. WithOrderBy("a", ASC, "b", DESC, NullsLast).
+func WithOrderBy(args ...interface{}) Params {
+	var orders []OrderStruct
+	for i := 0; i < len(args); {
+		key, ok := args[i].(string)
+		if !ok || i+1 >= len(args) {
+			panic(fmt.Errorf("error: invalid order arguments, expected key and order type at position %d", i))
+		}
+		value, ok := args[i+1].(OrderType)
+		if !ok {
+			panic(fmt.Errorf("error: invalid order type for key %s", key))
+		}
+		order := OrderStruct{key: key, value: value}
+		i += 2
+		if i < len(args) {
+			if nulls, ok := args[i].(NullsOrder); ok {
+				order.nulls = nulls
+				i++
+			}
+		}
+		orders = append(orders, order)
+	}
+	return func() QueryStruct {
+		return QueryStruct{
+			key:   QuerySort,
+			value: orders,
+		}
+	}
+}
+
+// Collation describes language aware string comparison for filters and sorts.
+// Locale defaults to "en" when empty.
+type Collation struct {
+	Locale          string
+	CaseInsensitive bool
+	NumericOrdering bool
+}
+
+// WithCollation compares strings using the rules of the given collation.
+func WithCollation(value Collation) Params {
+	return func() QueryStruct {
+		return QueryStruct{
+			key:   QueryCollation,
+			value: value,
 		}
 	}
 }
@@ -106,3 +190,46 @@ func WithOffset(value int64) Params {
 		}
 	}
 }
+
+// WithBatchSize sets the number of documents fetched per round trip when
+// streaming results with Iter or Each.
+func WithBatchSize(value int32) Params {
+	return func() QueryStruct {
+		return QueryStruct{
+			key:   QueryBatch,
+			value: value,
+		}
+	}
+}
+
+// WithPreload fills the named relation fields of the returned documents,
+// loading the related documents of all results in one query per relation.
+func WithPreload(names ...string) Params {
+	return func() QueryStruct {
+		return QueryStruct{
+			key:   QueryPreload,
+			value: names,
+		}
+	}
+}
+
+// UseScope applies the params the model registered under each name with
+// Scope.
+func UseScope(names ...string) Params {
+	return func() QueryStruct {
+		return QueryStruct{
+			key:   QueryScope,
+			value: names,
+		}
+	}
+}
+
+// Unscoped leaves out the default scopes of the model. It doesn't lift tenant
+// scoping.
+func Unscoped() Params {
+	return func() QueryStruct {
+		return QueryStruct{
+			key: QueryUnscoped,
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithOrderBy(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []interface{}
+		want      []OrderStruct
+		wantPanic bool
+	}{
+		{
+			name: "Test Multiple Keys",
+			args: []interface{}{"a", ASC, "b", DESC},
+			want: []OrderStruct{
+				{key: "a", value: ASC},
+				{key: "b", value: DESC},
+			},
+		},
+		{
+			name: "Test Null Placement",
+			args: []interface{}{"a", ASC, NullsLast, "b", DESC},
+			want: []OrderStruct{
+				{key: "a", value: ASC, nulls: NullsLast},
+				{key: "b", value: DESC},
+			},
+		},
+		{
+			name:      "Test Missing Order Type",
+			args:      []interface{}{"a"},
+			wantPanic: true,
+		},
+		{
+			name:      "Test Invalid Order Type",
+			args:      []interface{}{"a", 1},
+			wantPanic: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantPanic {
+				require.Panics(t, func() { WithOrderBy(tt.args...) })
+				return
+			}
+			got := WithOrderBy(tt.args...)()
+			require.Equal(t, QuerySort, got.Key())
+			require.Equal(t, tt.want, got.Value())
+		})
+	}
+}
+
+func TestWithOrder(t *testing.T) {
+	got := WithOrder("a", DESC, NullsFirst)().Value().(OrderStruct)
+	require.Equal(t, "a", got.Key())
+	require.Equal(t, DESC, got.Value())
+	require.Equal(t, NullsFirst, got.Nulls())
+}
@@ -61,10 +61,10 @@ func parse(struct_tag string, obj interface{}) ([]parsedField, error) {
 		if !ok {
 			return nil, errors.New("struct tag expected, got empty")
 		}
-		if strings.Contains(def, skipFieldTag) {
+		attr := strings.Split(def, ",")
+		if attr[0] == skipFieldTag {
 			continue
 		}
-		attr := strings.Split(def, ",")
 		single.fieldTag = attr
 		sliceOfParsed = append(sliceOfParsed, *single)
         single.Release()
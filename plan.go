@@ -0,0 +1,48 @@
+package database
+
+import (
+	"slices"
+	"time"
+)
+
+// ExplainVerbosity selects how much work Explain does.
+type ExplainVerbosity string
+
+const (
+	// ExplainQueryPlanner only selects the winning plan, without running it.
+	ExplainQueryPlanner ExplainVerbosity = "queryPlanner"
+	// ExplainExecutionStats runs the winning plan and reports its work.
+	ExplainExecutionStats ExplainVerbosity = "executionStats"
+	// ExplainAllPlans also runs the rejected candidate plans.
+	ExplainAllPlans ExplainVerbosity = "allPlansExecution"
+)
+
+// Plan summarizes how the database runs a query. The counters and execution
+// time are only filled in when the plan was executed.
+type Plan struct {
+	// Stages lists the stages of the winning plan, outermost first
+	Stages []string
+	// Indexes lists the names of the indexes scanned by the winning plan
+	Indexes []string
+	// CollectionScan is set when the winning plan reads the whole collection
+	CollectionScan bool
+	// Summary renders the winning plan on one line
+	Summary       string
+	KeysExamined  int64
+	DocsExamined  int64
+	DocsReturned  int64
+	ExecutionTime time.Duration
+	// Raw is the explain reply of the backend, as JSON
+	Raw []byte
+}
+
+// UsesIndex reports whether the plan reads through an index rather than
+// scanning the collection.
+func (p Plan) UsesIndex() bool {
+	return !p.CollectionScan && len(p.Indexes) > 0
+}
+
+// UsesIndexNamed reports whether the plan scans the named index.
+func (p Plan) UsesIndexNamed(name string) bool {
+	return slices.Contains(p.Indexes, name)
+}
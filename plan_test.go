@@ -0,0 +1,48 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanUsesIndex(t *testing.T) {
+	tests := []struct {
+		name      string
+		plan      Plan
+		index     string
+		want      bool
+		wantNamed bool
+	}{
+		{
+			name:      "Test Index Scan",
+			plan:      Plan{Stages: []string{"FETCH", "IXSCAN"}, Indexes: []string{"email_1"}},
+			index:     "email_1",
+			want:      true,
+			wantNamed: true,
+		},
+		{
+			name:  "Test Other Index",
+			plan:  Plan{Stages: []string{"FETCH", "IXSCAN"}, Indexes: []string{"name_1"}},
+			index: "email_1",
+			want:  true,
+		},
+		{
+			name:  "Test Collection Scan",
+			plan:  Plan{Stages: []string{"COLLSCAN"}, CollectionScan: true},
+			index: "email_1",
+		},
+		{
+			name:      "Test Partial Collection Scan",
+			plan:      Plan{Stages: []string{"OR", "IXSCAN", "COLLSCAN"}, Indexes: []string{"email_1"}, CollectionScan: true},
+			index:     "email_1",
+			wantNamed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.plan.UsesIndex())
+			require.Equal(t, tt.wantNamed, tt.plan.UsesIndexNamed(tt.index))
+		})
+	}
+}
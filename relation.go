@@ -0,0 +1,194 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const (
+	relationTag = "rel"
+
+	propertyLocal   = "local"
+	propertyForeign = "foreign"
+	propertyThrough = "through"
+)
+
+// RelationKind is how the documents of two models refer to each other.
+type RelationKind string
+
+const (
+	// BelongsTo loads the documents whose id is held by a field of the model
+	BelongsTo RelationKind = "belongs_to"
+	// HasOne loads the document holding the id of the model in a field
+	HasOne RelationKind = "has_one"
+	// HasMany loads every document holding the id of the model in a field
+	HasMany RelationKind = "has_many"
+	// ManyToMany loads the documents paired with the model in a join
+	// collection
+	ManyToMany RelationKind = "many_to_many"
+)
+
+// Relation describes a struct field filled with related documents when the
+// query preloads it. Keys are db keys, the id being _id.
+type Relation struct {
+	// Name is the struct field, as given to WithPreload
+	Name string
+	Kind RelationKind
+	// Collection holds the related documents
+	Collection string
+	// Type is the struct type of the related documents
+	Type reflect.Type
+	// Many is set when the field is a slice
+	Many bool
+	// LocalKey is the key of the model matched against ForeignKey on the
+	// related documents. For ManyToMany both are keys of the join collection
+	// documents, referring to the model and the related document
+	// respectively.
+	LocalKey   string
+	ForeignKey string
+	// Through is the join collection of a ManyToMany relation
+	Through string
+}
+
+// RelationsOf describes the relations declared on a model with rel tags. A
+// relation field must be tagged db:"-" so it isn't stored, and be a struct,
+// a pointer to one, or a slice of either:
+//
+//	AuthorID string     `db:"author_id,ref=users"`
+//	Author   *User      `db:"-" rel:"belongs_to,local=author_id"`
+//	Profile  *Profile   `db:"-" rel:"has_one,ref=profiles,foreign=user_id"`
+//	Comments []*Comment `db:"-" rel:"has_many,ref=comments,foreign=post_id"`
+//	Tags     []Tag      `db:"-" rel:"many_to_many,ref=tags,through=post_tags,local=post_id,foreign=tag_id"`
+//
+// A belongs_to relation takes its collection from the ref of its local key,
+// which may hold a list of ids when the field is a slice. has_one and
+// has_many match the model id unless local= names another key.
+func RelationsOf(model interface{}) ([]Relation, error) {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf(ErrNotStruct.Error(), reflect.Struct.String(), reflect.ValueOf(model).Kind().String())
+	}
+	fields, err := FieldsOf(model)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Relation
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		def, ok := sf.Tag.Lookup(relationTag)
+		if !ok {
+			continue
+		}
+		if sf.Tag.Get(databaseTag) != skipFieldTag {
+			return nil, fmt.Errorf("error: relation field %s must be tagged db:\"-\"", sf.Name)
+		}
+		tags := strings.Split(def, ",")
+		r := Relation{Name: sf.Name, Kind: RelationKind(tags[0])}
+		r.Type, r.Many = relationType(sf.Type)
+		if r.Type == nil {
+			return nil, fmt.Errorf("error: relation field %s must be a struct or a slice of structs, got %s", sf.Name, sf.Type)
+		}
+		r.Collection, _ = tagValue(tags, propertyRef)
+		r.LocalKey, _ = tagValue(tags, propertyLocal)
+		r.ForeignKey, _ = tagValue(tags, propertyForeign)
+		r.Through, _ = tagValue(tags, propertyThrough)
+
+		switch r.Kind {
+		case BelongsTo:
+			local, ok := LookupField(fields, r.LocalKey)
+			if !ok {
+				return nil, fmt.Errorf("error: relation %s refers to unknown field %q", sf.Name, r.LocalKey)
+			}
+			if r.Collection == "" {
+				r.Collection = local.Ref
+			}
+			if r.ForeignKey == "" {
+				r.ForeignKey = "_id"
+			}
+			if r.Many && local.Type.Kind() != reflect.Slice {
+				return nil, fmt.Errorf("error: relation %s is a list but %s holds a single id", sf.Name, r.LocalKey)
+			}
+		case HasOne, HasMany:
+			if r.LocalKey == "" {
+				r.LocalKey = "_id"
+			}
+			if r.ForeignKey == "" {
+				return nil, fmt.Errorf("error: relation %s has no foreign key", sf.Name)
+			}
+			if r.Many != (r.Kind == HasMany) {
+				return nil, fmt.Errorf("error: %s relation %s has the wrong field type %s", r.Kind, sf.Name, sf.Type)
+			}
+		case ManyToMany:
+			if r.Through == "" || r.LocalKey == "" || r.ForeignKey == "" {
+				return nil, fmt.Errorf("error: relation %s needs through, local and foreign keys", sf.Name)
+			}
+			if !r.Many {
+				return nil, fmt.Errorf("error: %s relation %s has the wrong field type %s", r.Kind, sf.Name, sf.Type)
+			}
+		default:
+			return nil, fmt.Errorf("error: unknown relation kind %q on field %s", tags[0], sf.Name)
+		}
+		if r.Collection == "" {
+			return nil, fmt.Errorf("error: relation %s has no collection", sf.Name)
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+// relationType returns the struct type behind a relation field and whether
+// the field holds a list of them.
+func relationType(t reflect.Type) (reflect.Type, bool) {
+	many := t.Kind() == reflect.Slice
+	if many {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, false
+	}
+	return t, many
+}
+
+// LookupRelation finds the relation filling the struct field name.
+func LookupRelation(relations []Relation, name string) (Relation, bool) {
+	for _, r := range relations {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return Relation{}, false
+}
+
+// SetRelation fills the relation field of doc, a pointer to the model, with
+// the related documents given as pointers to their struct type. A single
+// valued field gets the first of them, or is left zero when there is none.
+func SetRelation(doc any, r Relation, related []reflect.Value) {
+	field := reflect.ValueOf(doc).Elem().FieldByName(r.Name)
+	if !r.Many {
+		if len(related) > 0 {
+			field.Set(relationValue(field.Type(), related[0]))
+		}
+		return
+	}
+	res := reflect.MakeSlice(field.Type(), 0, len(related))
+	for _, v := range related {
+		res = reflect.Append(res, relationValue(field.Type().Elem(), v))
+	}
+	field.Set(res)
+}
+
+// relationValue dereferences the pointer v unless t is a pointer as well.
+func relationValue(t reflect.Type, v reflect.Value) reflect.Value {
+	if t.Kind() == reflect.Pointer {
+		return v
+	}
+	return v.Elem()
+}
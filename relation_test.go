@@ -0,0 +1,120 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type relUser struct {
+	ID   string `db:"mongoid"`
+	Name string `db:"name"`
+}
+
+func TestRelationsOf(t *testing.T) {
+	type post struct {
+		ID        string     `db:"mongoid"`
+		AuthorID  string     `db:"author_id,ref=users"`
+		EditorIDs []string   `db:"editor_ids,ref=users"`
+		Author    *relUser   `db:"-" rel:"belongs_to,local=author_id"`
+		Editors   []relUser  `db:"-" rel:"belongs_to,local=editor_ids"`
+		Owner     relUser    `db:"-" rel:"has_one,ref=users,foreign=post_id"`
+		Readers   []*relUser `db:"-" rel:"has_many,ref=users,foreign=read_id"`
+		Likes     []relUser  `db:"-" rel:"many_to_many,ref=users,through=likes,local=post_id,foreign=user_id"`
+	}
+	userType := reflect.TypeOf(relUser{})
+	tests := []struct {
+		name    string
+		model   interface{}
+		want    []Relation
+		wantErr bool
+	}{
+		{
+			name:  "Test Valid Model",
+			model: post{},
+			want: []Relation{
+				{Name: "Author", Kind: BelongsTo, Collection: "users", Type: userType, LocalKey: "author_id", ForeignKey: "_id"},
+				{Name: "Editors", Kind: BelongsTo, Collection: "users", Type: userType, Many: true, LocalKey: "editor_ids", ForeignKey: "_id"},
+				{Name: "Owner", Kind: HasOne, Collection: "users", Type: userType, LocalKey: "_id", ForeignKey: "post_id"},
+				{Name: "Readers", Kind: HasMany, Collection: "users", Type: userType, Many: true, LocalKey: "_id", ForeignKey: "read_id"},
+				{Name: "Likes", Kind: ManyToMany, Collection: "users", Type: userType, Many: true, LocalKey: "post_id", ForeignKey: "user_id", Through: "likes"},
+			},
+		},
+		{
+			name: "Test Stored Relation",
+			model: struct {
+				Owner relUser `db:"owner" rel:"has_one,ref=users,foreign=post_id"`
+			}{},
+			wantErr: true,
+		},
+		{
+			name: "Test Missing Ref",
+			model: struct {
+				OwnerID string   `db:"owner_id"`
+				Owner   *relUser `db:"-" rel:"belongs_to,local=owner_id"`
+			}{},
+			wantErr: true,
+		},
+		{
+			name: "Test Single Has Many",
+			model: struct {
+				Reader relUser `db:"-" rel:"has_many,ref=users,foreign=read_id"`
+			}{},
+			wantErr: true,
+		},
+		{
+			name: "Test Missing Join",
+			model: struct {
+				Likes []relUser `db:"-" rel:"many_to_many,ref=users,local=post_id,foreign=user_id"`
+			}{},
+			wantErr: true,
+		},
+		{
+			name: "Test Not A Struct Relation",
+			model: struct {
+				Names []string `db:"-" rel:"has_many,ref=users,foreign=read_id"`
+			}{},
+			wantErr: true,
+		},
+		{
+			name: "Test Unknown Kind",
+			model: struct {
+				Owner relUser `db:"-" rel:"owns,ref=users"`
+			}{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RelationsOf(tt.model)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSetRelation(t *testing.T) {
+	type post struct {
+		Author  *relUser  `db:"-"`
+		Owner   relUser   `db:"-"`
+		Editors []relUser `db:"-"`
+	}
+	ann, bob := &relUser{Name: "Ann"}, &relUser{Name: "Bob"}
+	related := []reflect.Value{reflect.ValueOf(ann), reflect.ValueOf(bob)}
+
+	var p post
+	SetRelation(&p, Relation{Name: "Author"}, related)
+	SetRelation(&p, Relation{Name: "Owner"}, related)
+	SetRelation(&p, Relation{Name: "Editors", Many: true}, related)
+	require.Same(t, ann, p.Author)
+	require.Equal(t, *ann, p.Owner)
+	require.Equal(t, []relUser{*ann, *bob}, p.Editors)
+
+	SetRelation(&p, Relation{Name: "Editors", Many: true}, nil)
+	require.Equal(t, []relUser{}, p.Editors)
+}
@@ -0,0 +1,205 @@
+package database
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaTypes is the JSON Schema type keyword. It is written as a single
+// string, or as a list when a field is nullable.
+type SchemaTypes []string
+
+func (s SchemaTypes) MarshalJSON() ([]byte, error) {
+	if len(s) == 1 {
+		return json.Marshal(s[0])
+	}
+	return json.Marshal([]string(s))
+}
+
+func (s SchemaTypes) MarshalYAML() (interface{}, error) {
+	if len(s) == 1 {
+		return s[0], nil
+	}
+	return []string(s), nil
+}
+
+// Schema is a JSON Schema (draft 2020-12, as used by OpenAPI 3.1) describing
+// the stored shape of a model.
+type Schema struct {
+	Title                string             `json:"title,omitempty" yaml:"title,omitempty"`
+	Type                 SchemaTypes        `json:"type,omitempty" yaml:"type,omitempty"`
+	Format               string             `json:"format,omitempty" yaml:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty" yaml:"maximum,omitempty"`
+	MinLength            *int64             `json:"minLength,omitempty" yaml:"minLength,omitempty"`
+	MaxLength            *int64             `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
+	MinItems             *int64             `json:"minItems,omitempty" yaml:"minItems,omitempty"`
+	MaxItems             *int64             `json:"maxItems,omitempty" yaml:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty" yaml:"required,omitempty"`
+}
+
+// JSON returns the schema as indented JSON.
+func (s *Schema) JSON() ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// YAML returns the schema as YAML.
+func (s *Schema) YAML() ([]byte, error) {
+	return yaml.Marshal(s)
+}
+
+// Components is an OpenAPI components object holding model schemas keyed by
+// their title, ready to be merged into an API description.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas" yaml:"schemas"`
+}
+
+func NewComponents(schemas ...*Schema) Components {
+	c := Components{Schemas: map[string]*Schema{}}
+	for _, s := range schemas {
+		c.Schemas[s.Title] = s
+	}
+	return c
+}
+
+// JSON returns the components as indented JSON.
+func (c Components) JSON() ([]byte, error) {
+	return json.MarshalIndent(map[string]Components{"components": c}, "", "  ")
+}
+
+// YAML returns the components as YAML.
+func (c Components) YAML() ([]byte, error) {
+	return yaml.Marshal(map[string]Components{"components": c})
+}
+
+var (
+	tUUID = reflect.TypeOf(uuid.UUID{})
+	tTime = reflect.TypeOf(time.Time{})
+)
+
+// SchemaOf builds the JSON Schema of a model from its db tags. Pointers, slices,
+// maps and interfaces are nullable since nil values are stored as null, nested
+// structs with db tags are described field by field, and uuid.UUID and
+// time.Time are strings with the uuid and date-time formats.
+func SchemaOf[T any]() (*Schema, error) {
+	var model T
+	return structSchema(reflect.TypeOf(model), map[reflect.Type]bool{})
+}
+
+func structSchema(t reflect.Type, seen map[reflect.Type]bool) (*Schema, error) {
+	fields, err := FieldsOf(reflect.Zero(t).Interface())
+	if err != nil {
+		return nil, err
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	res := &Schema{
+		Title:      t.Name(),
+		Type:       SchemaTypes{"object"},
+		Properties: map[string]*Schema{},
+	}
+	for _, f := range fields {
+		prop := typeSchema(f.Type, seen)
+		if f.MongoID {
+			prop.Pattern = "^[0-9a-f]{24}$"
+		}
+		if prop.Enum, err = f.EnumValues(); err != nil {
+			return nil, err
+		}
+		applyBounds(prop, f)
+		res.Properties[f.Key] = prop
+		if f.Required {
+			res.Required = append(res.Required, f.Key)
+		}
+	}
+	return res, nil
+}
+
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+
+	res := &Schema{}
+	switch {
+	case t == tUUID:
+		res.Type, res.Format = SchemaTypes{"string"}, "uuid"
+	case t == tTime:
+		res.Type, res.Format = SchemaTypes{"string"}, "date-time"
+	default:
+		switch t.Kind() {
+		case reflect.String:
+			res.Type = SchemaTypes{"string"}
+		case reflect.Bool:
+			res.Type = SchemaTypes{"boolean"}
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+			res.Type, res.Format = SchemaTypes{"integer"}, "int32"
+		case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+			res.Type, res.Format = SchemaTypes{"integer"}, "int64"
+		case reflect.Float32:
+			res.Type, res.Format = SchemaTypes{"number"}, "float"
+		case reflect.Float64:
+			res.Type, res.Format = SchemaTypes{"number"}, "double"
+		case reflect.Slice, reflect.Array:
+			if t.Elem().Kind() == reflect.Uint8 {
+				res.Type, res.Format = SchemaTypes{"string"}, "byte"
+				break
+			}
+			res.Type, res.Items = SchemaTypes{"array"}, typeSchema(t.Elem(), seen)
+			nullable = nullable || t.Kind() == reflect.Slice
+		case reflect.Map:
+			res.Type, res.AdditionalProperties = SchemaTypes{"object"}, typeSchema(t.Elem(), seen)
+			nullable = true
+		case reflect.Struct:
+			// nested models are described when they carry db tags and are not
+			// already being described further up, which would recurse forever
+			if !seen[t] {
+				if nested, err := structSchema(t, seen); err == nil {
+					nested.Title = ""
+					res = nested
+					break
+				}
+			}
+			res.Type = SchemaTypes{"object"}
+		case reflect.Interface:
+			return res
+		}
+	}
+	if nullable {
+		res.Type = append(res.Type, "null")
+	}
+	return res
+}
+
+func applyBounds(s *Schema, f Field) {
+	t := f.Type
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	length := func(v *float64) *int64 {
+		if v == nil {
+			return nil
+		}
+		n := int64(*v)
+		return &n
+	}
+	switch t.Kind() {
+	case reflect.String:
+		s.MinLength, s.MaxLength = length(f.Min), length(f.Max)
+	case reflect.Slice, reflect.Array:
+		s.MinItems, s.MaxItems = length(f.Min), length(f.Max)
+	default:
+		s.Minimum, s.Maximum = f.Min, f.Max
+	}
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type schemaAddress struct {
+	City string `db:"city,required"`
+}
+
+type schemaUser struct {
+	ID        string            `db:"mongoid"`
+	UID       uuid.UUID         `db:"uid"`
+	Email     string            `db:"email,required,max=254"`
+	Level     int8              `db:"level,enum=1|2"`
+	Nickname  *string           `db:"nickname"`
+	Tags      []string          `db:"tags"`
+	Meta      map[string]int    `db:"meta"`
+	Address   schemaAddress     `db:"address"`
+	Previous  *schemaUser       `db:"previous"`
+	CreatedAt time.Time         `db:"created_at"`
+	Extra     interface{}       `db:"extra"`
+	Raw       map[string][]byte `db:"raw"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	got, err := SchemaOf[schemaUser]()
+	require.NoError(t, err)
+
+	max := int64(254)
+	require.Equal(t, "schemaUser", got.Title)
+	require.Equal(t, []string{"email"}, got.Required)
+	require.Equal(t, &Schema{Type: SchemaTypes{"string"}, Pattern: "^[0-9a-f]{24}$"}, got.Properties["_id"])
+	require.Equal(t, &Schema{Type: SchemaTypes{"string"}, Format: "uuid"}, got.Properties["uid"])
+	require.Equal(t, &Schema{Type: SchemaTypes{"string"}, MaxLength: &max}, got.Properties["email"])
+	require.Equal(t, &Schema{Type: SchemaTypes{"integer"}, Format: "int32", Enum: []interface{}{int64(1), int64(2)}}, got.Properties["level"])
+	require.Equal(t, &Schema{Type: SchemaTypes{"string", "null"}}, got.Properties["nickname"])
+	require.Equal(t, &Schema{Type: SchemaTypes{"array", "null"}, Items: &Schema{Type: SchemaTypes{"string"}}}, got.Properties["tags"])
+	require.Equal(t, &Schema{Type: SchemaTypes{"object", "null"}, AdditionalProperties: &Schema{Type: SchemaTypes{"integer"}, Format: "int64"}}, got.Properties["meta"])
+	require.Equal(t, &Schema{
+		Type:       SchemaTypes{"object"},
+		Properties: map[string]*Schema{"city": {Type: SchemaTypes{"string"}}},
+		Required:   []string{"city"},
+	}, got.Properties["address"])
+	require.Equal(t, &Schema{Type: SchemaTypes{"object", "null"}}, got.Properties["previous"])
+	require.Equal(t, &Schema{Type: SchemaTypes{"string"}, Format: "date-time"}, got.Properties["created_at"])
+	require.Equal(t, &Schema{}, got.Properties["extra"])
+
+	t.Run("Test Invalid Model", func(t *testing.T) {
+		_, err := SchemaOf[struct{ Name string }]()
+		require.Error(t, err)
+	})
+}
+
+func TestSchemaExport(t *testing.T) {
+	schema, err := SchemaOf[schemaAddress]()
+	require.NoError(t, err)
+
+	t.Run("Test JSON", func(t *testing.T) {
+		data, err := NewComponents(schema).JSON()
+		require.NoError(t, err)
+		var got map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &got))
+		require.Equal(t, map[string]interface{}{
+			"components": map[string]interface{}{
+				"schemas": map[string]interface{}{
+					"schemaAddress": map[string]interface{}{
+						"title":      "schemaAddress",
+						"type":       "object",
+						"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+						"required":   []interface{}{"city"},
+					},
+				},
+			},
+		}, got)
+	})
+
+	t.Run("Test YAML", func(t *testing.T) {
+		data, err := schema.YAML()
+		require.NoError(t, err)
+		var got map[string]interface{}
+		require.NoError(t, yaml.Unmarshal(data, &got))
+		require.Equal(t, "object", got["type"])
+		require.Equal(t, []interface{}{"city"}, got["required"])
+	})
+
+	t.Run("Test Nullable Type", func(t *testing.T) {
+		data, err := json.Marshal(SchemaTypes{"string", "null"})
+		require.NoError(t, err)
+		require.JSONEq(t, `["string", "null"]`, string(data))
+	})
+}
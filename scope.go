@@ -0,0 +1,90 @@
+package database
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Scopes holds the named and default scopes of a model. Backends expand the
+// params of every query with it, so a scope is defined once and used by name.
+// Scopes are meant to be registered at startup; it is safe for concurrent
+// use.
+type Scopes struct {
+	mu       sync.RWMutex
+	named    map[string][]Params
+	defaults []Params
+}
+
+// Add registers params under name, applied by queries given UseScope(name).
+// Registering a name again replaces its params.
+func (s *Scopes) Add(name string, params ...Params) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.named == nil {
+		s.named = map[string][]Params{}
+	}
+	s.named[name] = params
+}
+
+// AddDefault registers params applied to every query not given Unscoped.
+func (s *Scopes) AddDefault(params ...Params) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaults = append(s.defaults, params...)
+}
+
+// Expand replaces the scopes used by params with their own params, which may
+// use other scopes in turn, and puts the default scopes first unless params
+// contain Unscoped. A nil Scopes only drops the Unscoped params.
+func (s *Scopes) Expand(params []Params) ([]Params, error) {
+	if s == nil {
+		s = &Scopes{}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res, unscoped, err := s.expand(params, nil)
+	if err != nil {
+		return nil, err
+	}
+	if unscoped || len(s.defaults) == 0 {
+		return res, nil
+	}
+	defaults, _, err := s.expand(s.defaults, nil)
+	if err != nil {
+		return nil, err
+	}
+	return append(defaults, res...), nil
+}
+
+func (s *Scopes) expand(params []Params, using []string) ([]Params, bool, error) {
+	var res []Params
+	var unscoped bool
+	for _, param := range params {
+		qs := param()
+		switch qs.Key() {
+		case QueryUnscoped:
+			unscoped = true
+		case QueryScope:
+			for _, name := range qs.Value().([]string) {
+				scope, ok := s.named[name]
+				if !ok {
+					return nil, false, fmt.Errorf("error: unknown scope %s", name)
+				}
+				for _, u := range using {
+					if u == name {
+						return nil, false, fmt.Errorf("error: scope %s uses itself", name)
+					}
+				}
+				expanded, u, err := s.expand(scope, append(using, name))
+				if err != nil {
+					return nil, false, err
+				}
+				res, unscoped = append(res, expanded...), unscoped || u
+			}
+		default:
+			res = append(res, param)
+		}
+	}
+	return res, unscoped, nil
+}
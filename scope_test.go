@@ -0,0 +1,80 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopes(t *testing.T) {
+	var s Scopes
+	s.Add("active", WithFilter("status", "active"))
+	s.Add("recent", WithOrder("created_at", DESC), WithLimit(10))
+	s.Add("feed", UseScope("active", "recent"))
+	s.Add("loop", UseScope("loop"))
+	s.AddDefault(WithFilter("archived", false))
+
+	tests := []struct {
+		name    string
+		params  []Params
+		want    []Params
+		wantErr bool
+	}{
+		{
+			name:   "Test Default Scope",
+			params: []Params{WithLimit(5)},
+			want:   []Params{WithFilter("archived", false), WithLimit(5)},
+		},
+		{
+			name:   "Test Named Scope",
+			params: []Params{UseScope("active"), WithLimit(5)},
+			want:   []Params{WithFilter("archived", false), WithFilter("status", "active"), WithLimit(5)},
+		},
+		{
+			name:   "Test Nested Scopes",
+			params: []Params{Unscoped(), UseScope("feed")},
+			want:   []Params{WithFilter("status", "active"), WithOrder("created_at", DESC), WithLimit(10)},
+		},
+		{
+			name:   "Test Unscoped",
+			params: []Params{UseScope("active"), Unscoped()},
+			want:   []Params{WithFilter("status", "active")},
+		},
+		{
+			name:    "Test Unknown Scope",
+			params:  []Params{UseScope("deleted")},
+			wantErr: true,
+		},
+		{
+			name:    "Test Recursive Scope",
+			params:  []Params{UseScope("loop")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Expand(tt.params)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, evalParams(tt.want), evalParams(got))
+		})
+	}
+}
+
+func TestNilScopes(t *testing.T) {
+	var s *Scopes
+	got, err := s.Expand([]Params{Unscoped(), WithLimit(5)})
+	require.NoError(t, err)
+	require.Equal(t, evalParams([]Params{WithLimit(5)}), evalParams(got))
+}
+
+func evalParams(params []Params) []QueryStruct {
+	var res []QueryStruct
+	for _, p := range params {
+		res = append(res, p())
+	}
+	return res
+}
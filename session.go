@@ -0,0 +1,320 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+var (
+	ErrNoID = errors.New("error: model has no mongoid field or its value is empty")
+)
+
+// Transactor is implemented by backends able to run a function atomically.
+// fn must use the context it is given for the writes to join the transaction,
+// and may be called again when the transaction is retried.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Binder is implemented by models able to hand out a copy of themselves bound
+// to a context, leaving the model they were called on untouched.
+type Binder[T any] interface {
+	Bind(ctx context.Context) Model[T]
+}
+
+// Identifier is implemented by models able to name the documents they address
+// under ctx, such as their collection and the tenant they are scoped to.
+// Sessions tell the documents of two models apart with it.
+type Identifier interface {
+	Identity(ctx context.Context) (string, error)
+}
+
+type identityKey struct {
+	model any
+	id    string
+}
+
+type sessionEntity struct {
+	doc      any
+	snapshot M
+	encode   func() (M, error)
+	flush    func(ctx context.Context, fields []string) error
+}
+
+// Session is a unit of work. Models loaded through it are kept in an identity
+// map, so loading the same id twice returns the same pointer, and Commit
+// writes back only the fields changed since they were loaded. A Session is
+// meant for a single request and is not safe for concurrent use.
+type Session struct {
+	ctx      context.Context
+	tx       Transactor
+	entities map[identityKey]*sessionEntity
+	order    []identityKey
+	inserts  []func(ctx context.Context) error
+}
+
+// NewSession starts a unit of work. When tx is not nil Commit runs inside one
+// of its transactions.
+func NewSession(ctx context.Context, tx Transactor) *Session {
+	return &Session{
+		ctx:      ctx,
+		tx:       tx,
+		entities: map[identityKey]*sessionEntity{},
+	}
+}
+
+// Load returns the document of model with the given id, from the identity
+// map when it was already loaded or attached to the session.
+func Load[T any](s *Session, model Model[T], id string) (*T, error) {
+	key, err := s.identity(model, id)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := s.entities[key]; ok {
+		return e.doc.(*T), nil
+	}
+	var doc *T
+	err = withModel(s, model, s.ctx, func(model Model[T]) (err error) {
+		doc, err = model.Query(WithFilter("_id", id)).First()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := track(s, key, model, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Attach adds a document loaded outside the session to its identity map, so
+// its changes are written on Commit. Attaching an id already in the session
+// returns the tracked pointer instead.
+func Attach[T any](s *Session, model Model[T], doc *T) (*T, error) {
+	id, err := modelID(*doc)
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.identity(model, id)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := s.entities[key]; ok {
+		return e.doc.(*T), nil
+	}
+	if err := track(s, key, model, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Add queues new documents to be saved on Commit.
+func Add[T any](s *Session, model Model[T], docs ...T) {
+	s.inserts = append(s.inserts, func(ctx context.Context) error {
+		return withModel(s, model, ctx, func(model Model[T]) error {
+			return model.Save(docs...)
+		})
+	})
+}
+
+// identity keys the documents of model under the session context. Models
+// that can't name their documents stand for them themselves.
+func (s *Session) identity(model any, id string) (identityKey, error) {
+	if m, ok := model.(Identifier); ok {
+		name, err := m.Identity(s.ctx)
+		if err != nil {
+			return identityKey{}, err
+		}
+		return identityKey{model: name, id: id}, nil
+	}
+	return identityKey{model: model, id: id}, nil
+}
+
+// withModel calls fn with model bound to ctx, through a handle of its own when
+// the model is a Binder. The others are bound in place and handed back the
+// session context after, so they don't outlive a transaction.
+func withModel[T any](s *Session, model Model[T], ctx context.Context, fn func(Model[T]) error) error {
+	if b, ok := model.(Binder[T]); ok {
+		return fn(b.Bind(ctx))
+	}
+	defer model.WithContext(s.ctx)
+	return fn(model.WithContext(ctx))
+}
+
+func track[T any](s *Session, key identityKey, model Model[T], doc *T) error {
+	// snapshots are compared in the clear, as encrypting a value twice
+	// rarely gives the same ciphertext
+	snapshot, err := encodeModel(*doc, false)
+	if err != nil {
+		return err
+	}
+	s.entities[key] = &sessionEntity{
+		doc:      doc,
+		snapshot: cloneModel(snapshot),
+		encode: func() (M, error) {
+			return encodeModel(*doc, false)
+		},
+		flush: func(ctx context.Context, fields []string) error {
+			return withModel(s, model, ctx, func(model Model[T]) error {
+				return model.Query(WithFilter("_id", key.id)).UpdateFields(*doc, fields...)
+			})
+		},
+	}
+	s.order = append(s.order, key)
+	return nil
+}
+
+// modelID returns the value of the mongoid field of doc.
+func modelID(doc any) (string, error) {
+	m, err := encodeModel(doc, false)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range m {
+		if p.MongoID {
+			if id, ok := p.Value.(string); ok && id != "" {
+				return id, nil
+			}
+		}
+	}
+	return "", ErrNoID
+}
+
+type sessionChange struct {
+	entity  *sessionEntity
+	current M
+	fields  []string
+}
+
+// Commit saves the queued documents and sets the changed fields of every
+// tracked document. Without a transaction, the writes that succeeded before
+// an error are not repeated by the next Commit.
+func (s *Session) Commit() error {
+	var changes []sessionChange
+	for _, key := range s.order {
+		e := s.entities[key]
+		current, err := e.encode()
+		if err != nil {
+			return err
+		}
+		if fields := changedFields(e.snapshot, current); len(fields) > 0 {
+			changes = append(changes, sessionChange{entity: e, current: current, fields: fields})
+		}
+	}
+	if len(changes) == 0 && len(s.inserts) == 0 {
+		return nil
+	}
+
+	if s.tx == nil {
+		return s.flush(s.ctx, changes, true)
+	}
+	err := s.tx.WithTransaction(s.ctx, func(ctx context.Context) error {
+		return s.flush(ctx, changes, false)
+	})
+	if err != nil {
+		return err
+	}
+	s.inserts = nil
+	for _, c := range changes {
+		c.entity.snapshot = cloneModel(c.current)
+	}
+	return nil
+}
+
+// flush runs the writes. With record set each successful write is recorded
+// as it happens, otherwise flush can be retried as a whole.
+func (s *Session) flush(ctx context.Context, changes []sessionChange, record bool) error {
+	for i, insert := range s.inserts {
+		if err := insert(ctx); err != nil {
+			if record {
+				s.inserts = s.inserts[i:]
+			}
+			return err
+		}
+	}
+	if record {
+		s.inserts = nil
+	}
+	for _, c := range changes {
+		if err := c.entity.flush(ctx, c.fields); err != nil {
+			return err
+		}
+		if record {
+			c.entity.snapshot = cloneModel(c.current)
+		}
+	}
+	return nil
+}
+
+// changedFields returns the keys whose value differs between two encodings
+// of the same model, leaving out the id.
+func changedFields(before, after M) []string {
+	prev := make(map[string]interface{}, len(before))
+	for _, p := range before {
+		prev[p.Key] = p.Value
+	}
+	var res []string
+	for _, p := range after {
+		if p.MongoID {
+			continue
+		}
+		if !reflect.DeepEqual(prev[p.Key], p.Value) {
+			res = append(res, p.Key)
+		}
+	}
+	return res
+}
+
+// cloneModel copies the slices and maps of an encoded model, so changes made
+// in place to the document don't reach its snapshot.
+func cloneModel(m M) M {
+	res := make(M, len(m))
+	for i, p := range m {
+		if p.Value != nil {
+			p.Value = cloneValue(reflect.ValueOf(p.Value)).Interface()
+		}
+		res[i] = p
+	}
+	return res
+}
+
+func cloneValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			res.Index(i).Set(cloneValue(v.Index(i)))
+		}
+		return res
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			res.SetMapIndex(iter.Key(), cloneValue(iter.Value()))
+		}
+		return res
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.New(v.Type().Elem())
+		res.Elem().Set(cloneValue(v.Elem()))
+		return res
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		res := reflect.New(v.Type()).Elem()
+		res.Set(cloneValue(v.Elem()))
+		return res
+	default:
+		return v
+	}
+}
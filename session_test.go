@@ -0,0 +1,212 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type sessionUser struct {
+	ID    string   `db:"mongoid"`
+	Name  string   `db:"name"`
+	Email string   `db:"email"`
+	Tags  []string `db:"tags"`
+}
+
+type fieldUpdate struct {
+	id     string
+	fields []string
+}
+
+// memoryModel serves First by id from memory and records the writes.
+type memoryModel struct {
+	Model[sessionUser]
+	docs    map[string]sessionUser
+	loads   int
+	updates []fieldUpdate
+	saved   []sessionUser
+	failOn  string
+	ctx     context.Context
+}
+
+func (m *memoryModel) WithContext(ctx context.Context) Model[sessionUser] {
+	m.ctx = ctx
+	return m
+}
+func (m *memoryModel) Query(params ...Params) Query[sessionUser] {
+	q := &memoryQuery{m: m}
+	for _, p := range params {
+		if f, ok := p().Value().(FilterStruct); ok && f.Key() == "_id" {
+			q.id = f.Value().(string)
+		}
+	}
+	return q
+}
+func (m *memoryModel) Save(doc ...sessionUser) error {
+	m.saved = append(m.saved, doc...)
+	return nil
+}
+func (m *memoryModel) ExecRaw() error { return nil }
+
+// memoryQuery implements the methods the session uses, the embedded nil
+// interface stands in for the others.
+type memoryQuery struct {
+	Query[sessionUser]
+	m  *memoryModel
+	id string
+}
+
+func (q *memoryQuery) First() (*sessionUser, error) {
+	q.m.loads++
+	doc, ok := q.m.docs[q.id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &doc, nil
+}
+func (q *memoryQuery) UpdateFields(doc sessionUser, fields ...string) error {
+	if q.id == q.m.failOn {
+		return errors.New("failed")
+	}
+	q.m.updates = append(q.m.updates, fieldUpdate{id: q.id, fields: fields})
+	return nil
+}
+
+type recordingTx struct {
+	calls int
+}
+
+type txKey struct{}
+
+func (tx *recordingTx) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx.calls++
+	return fn(context.WithValue(ctx, txKey{}, true))
+}
+
+func newMemoryModel() *memoryModel {
+	return &memoryModel{docs: map[string]sessionUser{
+		"a": {ID: "a", Name: "Ann", Email: "ann@example.com", Tags: []string{"x"}},
+		"b": {ID: "b", Name: "Bob", Email: "bob@example.com"},
+	}}
+}
+
+// namedModel is a Binder and Identifier over a memoryModel, recording the
+// contexts it is bound to.
+type namedModel struct {
+	*memoryModel
+	name  string
+	binds []context.Context
+}
+
+func (m *namedModel) Identity(ctx context.Context) (string, error) { return m.name, nil }
+func (m *namedModel) Bind(ctx context.Context) Model[sessionUser] {
+	m.binds = append(m.binds, ctx)
+	return m.memoryModel
+}
+
+func TestSessionIdentityMap(t *testing.T) {
+	model := newMemoryModel()
+	s := NewSession(context.Background(), nil)
+
+	first, err := Load[sessionUser](s, model, "a")
+	require.NoError(t, err)
+	second, err := Load[sessionUser](s, model, "a")
+	require.NoError(t, err)
+	require.Same(t, first, second)
+	require.Equal(t, 1, model.loads)
+
+	_, err = Load[sessionUser](s, model, "missing")
+	require.Error(t, err)
+
+	attached, err := Attach(s, model, &sessionUser{ID: "a"})
+	require.NoError(t, err)
+	require.Same(t, first, attached)
+
+	_, err = Attach(s, model, &sessionUser{Name: "no id"})
+	require.ErrorIs(t, err, ErrNoID)
+}
+
+func TestSessionModelIdentity(t *testing.T) {
+	users, admins := newMemoryModel(), newMemoryModel()
+	s := NewSession(context.Background(), &recordingTx{})
+
+	user, err := Load[sessionUser](s, &namedModel{memoryModel: users, name: "users"}, "a")
+	require.NoError(t, err)
+	adminModel := &namedModel{memoryModel: admins, name: "admins"}
+	admin, err := Load[sessionUser](s, adminModel, "a")
+	require.NoError(t, err)
+	require.NotSame(t, user, admin, "same id in another collection")
+
+	// another handle on the same collection shares the identity map
+	handle := &namedModel{memoryModel: users, name: "users"}
+	again, err := Load[sessionUser](s, handle, "a")
+	require.NoError(t, err)
+	require.Same(t, user, again)
+	require.Equal(t, 1, users.loads)
+
+	admin.Name = "Root"
+	require.NoError(t, s.Commit())
+	require.Equal(t, []fieldUpdate{{id: "a", fields: []string{"name"}}}, admins.updates)
+	require.Empty(t, users.updates)
+	require.Nil(t, admins.ctx, "bound handles leave the model untouched")
+	require.Len(t, adminModel.binds, 2)
+	require.Equal(t, true, adminModel.binds[1].Value(txKey{}), "the write runs in the transaction")
+}
+
+func TestSessionCommit(t *testing.T) {
+	model := newMemoryModel()
+	tx := &recordingTx{}
+	s := NewSession(context.Background(), tx)
+
+	require.NoError(t, s.Commit())
+	require.Zero(t, tx.calls, "nothing to write")
+
+	ann, err := Load[sessionUser](s, model, "a")
+	require.NoError(t, err)
+	bob, err := Load[sessionUser](s, model, "b")
+	require.NoError(t, err)
+
+	ann.Name = "Anna"
+	ann.Tags[0] = "y"
+	bob.Email = "robert@example.com"
+	Add(s, model, sessionUser{Name: "Cid"})
+
+	require.NoError(t, s.Commit())
+	require.Equal(t, 1, tx.calls)
+	require.Equal(t, []fieldUpdate{
+		{id: "a", fields: []string{"name", "tags"}},
+		{id: "b", fields: []string{"email"}},
+	}, model.updates)
+	require.Equal(t, []sessionUser{{Name: "Cid"}}, model.saved)
+	require.Nil(t, model.ctx.Value(txKey{}), "the model is handed back the session context")
+
+	require.NoError(t, s.Commit())
+	require.Equal(t, 1, tx.calls, "snapshots are refreshed after a commit")
+	require.Len(t, model.updates, 2)
+	require.Len(t, model.saved, 1)
+}
+
+func TestSessionCommitWithoutTransaction(t *testing.T) {
+	model := newMemoryModel()
+	model.failOn = "b"
+	s := NewSession(context.Background(), nil)
+
+	ann, err := Load[sessionUser](s, model, "a")
+	require.NoError(t, err)
+	bob, err := Load[sessionUser](s, model, "b")
+	require.NoError(t, err)
+	ann.Name = "Anna"
+	bob.Name = "Robert"
+
+	require.Error(t, s.Commit())
+	require.Equal(t, []fieldUpdate{{id: "a", fields: []string{"name"}}}, model.updates)
+
+	model.failOn = ""
+	require.NoError(t, s.Commit())
+	require.Equal(t, []fieldUpdate{
+		{id: "a", fields: []string{"name"}},
+		{id: "b", fields: []string{"name"}},
+	}, model.updates, "writes done before the error are not repeated")
+}
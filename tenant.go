@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"errors"
+)
+
+const propertyTenant = "tenant"
+
+var (
+	ErrNoTenant = errors.New("error: no tenant in context")
+)
+
+type tenantKey struct{}
+
+// WithTenant returns a context scoping the operations of models with a tenant
+// field, given to them with WithContext, to tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom returns the tenant set on ctx with WithTenant.
+func TenantFrom(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// TenantField returns the field marked with the tenant property, reporting
+// an error when several are.
+func TenantField(fields []Field) (Field, bool, error) {
+	var res Field
+	var found bool
+	for _, f := range fields {
+		if !f.Tenant {
+			continue
+		}
+		if found {
+			return Field{}, false, errors.New("error: model has more than one tenant field")
+		}
+		res, found = f, true
+	}
+	return res, found, nil
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantFrom(t *testing.T) {
+	_, ok := TenantFrom(context.Background())
+	require.False(t, ok)
+
+	_, ok = TenantFrom(WithTenant(context.Background(), ""))
+	require.False(t, ok, "an empty tenant is no tenant")
+
+	tenant, ok := TenantFrom(WithTenant(context.Background(), "acme"))
+	require.True(t, ok)
+	require.Equal(t, "acme", tenant)
+}
+
+func TestTenantField(t *testing.T) {
+	fields, err := FieldsOf(struct {
+		Tenant string `db:"tenant_id,tenant,index"`
+		Name   string `db:"name"`
+	}{})
+	require.NoError(t, err)
+	f, ok, err := TenantField(fields)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "tenant_id", f.Key)
+
+	fields, err = FieldsOf(struct {
+		Name string `db:"name"`
+	}{})
+	require.NoError(t, err)
+	_, ok, err = TenantField(fields)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	fields, err = FieldsOf(struct {
+		Tenant string `db:"tenant_id,tenant"`
+		Org    string `db:"org_id,tenant"`
+	}{})
+	require.NoError(t, err)
+	_, _, err = TenantField(fields)
+	require.Error(t, err)
+
+	fields, err = FieldsOf(struct {
+		Tenant string `db:"tenant"`
+	}{})
+	require.NoError(t, err)
+	_, ok, err = TenantField(fields)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
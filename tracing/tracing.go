@@ -0,0 +1,92 @@
+// Package tracing creates OpenTelemetry spans for model operations.
+//
+// Install the interceptor on a database handle or a single model:
+//
+//	conn.Use(tracing.Interceptor())
+//
+// Spans are children of the context given to WithContext and follow the
+// database client semantic conventions.
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/neghi-go/database"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/neghi-go/database/tracing"
+
+// Attribute keys from the database semantic conventions.
+const (
+	DBSystem         = attribute.Key("db.system")
+	DBName           = attribute.Key("db.name")
+	DBCollectionName = attribute.Key("db.collection.name")
+	DBOperationName  = attribute.Key("db.operation.name")
+	DBQueryText      = attribute.Key("db.query.text")
+)
+
+type config struct {
+	provider trace.TracerProvider
+}
+
+type Option func(*config)
+
+// WithTracerProvider sets the provider spans are created from. The global
+// provider is used by default.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = provider
+	}
+}
+
+// Interceptor returns an interceptor that wraps every operation in a client
+// span. Only the sanitized filter summary is recorded, never the values.
+func Interceptor(opts ...Option) database.Interceptor {
+	cfg := config{provider: otel.GetTracerProvider()}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	tracer := cfg.provider.Tracer(instrumentationName)
+
+	return func(ctx context.Context, op *database.OpInfo, next func(context.Context) error) error {
+		attrs := []attribute.KeyValue{
+			DBSystem.String(op.System),
+			DBName.String(op.Database),
+			DBCollectionName.String(op.Collection),
+			DBOperationName.String(op.Operation),
+		}
+		if op.Query != "" {
+			attrs = append(attrs, DBQueryText.String(op.Query))
+		}
+		start := time.Now()
+		ctx, span := tracer.Start(ctx, spanName(op),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attrs...),
+			trace.WithTimestamp(start))
+
+		// the operation runs under the span, so spans the driver or inner
+		// interceptors start are its children
+		err := next(ctx)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		// the span lasts as long as the operation reports, which leaves out
+		// the time a consumer spends between the batches of an iteration
+		span.End(trace.WithTimestamp(start.Add(op.Duration)))
+		return err
+	}
+}
+
+// spanName follows the "{operation} {collection}" convention.
+func spanName(op *database.OpInfo) string {
+	if op.Collection == "" {
+		return op.Operation
+	}
+	return op.Operation + " " + op.Collection
+}
@@ -0,0 +1,153 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/neghi-go/database"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recorder is a TracerProvider keeping the spans it starts, so the tests do
+// not depend on the SDK.
+type recorder struct {
+	noop.TracerProvider
+	spans []*recordedSpan
+}
+
+func (r *recorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{r: r}
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	r *recorder
+}
+
+func (t recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	parent := trace.SpanContextFromContext(ctx)
+	span := &recordedSpan{
+		name:   name,
+		kind:   cfg.SpanKind(),
+		attrs:  cfg.Attributes(),
+		parent: parent,
+		start:  cfg.Timestamp(),
+		sc: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1},
+			SpanID:     trace.SpanID{byte(len(t.r.spans) + 1)},
+			TraceFlags: trace.FlagsSampled,
+		}),
+	}
+	t.r.spans = append(t.r.spans, span)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type recordedSpan struct {
+	noop.Span
+	name   string
+	kind   trace.SpanKind
+	attrs  []attribute.KeyValue
+	parent trace.SpanContext
+	sc     trace.SpanContext
+	status codes.Code
+	errs   []error
+	start  time.Time
+	end    time.Time
+}
+
+func (s *recordedSpan) End(opts ...trace.SpanEndOption) {
+	cfg := trace.NewSpanEndConfig(opts...)
+	s.end = cfg.Timestamp()
+}
+
+func (s *recordedSpan) SpanContext() trace.SpanContext { return s.sc }
+
+func (s *recordedSpan) SetStatus(code codes.Code, _ string) { s.status = code }
+
+func (s *recordedSpan) RecordError(err error, _ ...trace.EventOption) { s.errs = append(s.errs, err) }
+
+func TestInterceptor(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name       string
+		op         database.OpInfo
+		err        error
+		wantName   string
+		wantAttrs  []attribute.KeyValue
+		wantStatus codes.Code
+	}{
+		{
+			name: "Test Find",
+			op: database.OpInfo{
+				System:     "mongodb",
+				Database:   "shop",
+				Collection: "orders",
+				Operation:  database.OpFind,
+				Query:      `{"customer":"?"}`,
+			},
+			wantName: "find orders",
+			wantAttrs: []attribute.KeyValue{
+				DBSystem.String("mongodb"),
+				DBName.String("shop"),
+				DBCollectionName.String("orders"),
+				DBOperationName.String("find"),
+				DBQueryText.String(`{"customer":"?"}`),
+			},
+			wantStatus: codes.Unset,
+		},
+		{
+			name: "Test Failed Insert",
+			op: database.OpInfo{
+				System:     "mongodb",
+				Database:   "shop",
+				Collection: "orders",
+				Operation:  database.OpInsert,
+			},
+			err:      errFailed,
+			wantName: "insert orders",
+			wantAttrs: []attribute.KeyValue{
+				DBSystem.String("mongodb"),
+				DBName.String("shop"),
+				DBCollectionName.String("orders"),
+				DBOperationName.String("insert"),
+			},
+			wantStatus: codes.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &recorder{}
+
+			ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+			var inner trace.SpanContext
+			err := database.Intercept(ctx, []database.Interceptor{Interceptor(WithTracerProvider(provider))}, &tt.op,
+				func(ctx context.Context) error {
+					inner = trace.SpanContextFromContext(ctx)
+					return tt.err
+				})
+			require.ErrorIs(t, err, tt.err)
+
+			require.Len(t, provider.spans, 2)
+			span := provider.spans[1]
+			require.Equal(t, tt.wantName, span.name)
+			require.Equal(t, trace.SpanKindClient, span.kind)
+			require.Equal(t, tt.wantAttrs, span.attrs)
+			require.Equal(t, tt.wantStatus, span.status)
+			require.Equal(t, parent.SpanContext().SpanID(), span.parent.SpanID())
+			// the operation runs with the span in its context
+			require.Equal(t, span.sc, inner)
+			if tt.err != nil {
+				require.Equal(t, []error{tt.err}, span.errs)
+			}
+			require.Equal(t, tt.op.Duration, span.end.Sub(span.start))
+		})
+	}
+}